@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmks/urlstat/hosts"
+	"github.com/jmks/urlstat/tld"
+)
+
+// checkConfig holds the options that change how link statuses are checked
+type checkConfig struct {
+	concurrency int
+	perHost     int
+	limitBy     string
+	method      string
+	timeout     time.Duration
+	retries     int
+	backoff     time.Duration
+}
+
+// result of checking the status of a link
+type result struct {
+	link
+	RequestURL string
+	Method     string
+	Status     string
+	StatusCode int
+	Redirects  []redirect
+	FinalURL   string
+	Err        error
+	Category   string
+	Detail     string
+	Duration   time.Duration
+}
+
+// redirect is a single hop of a redirect chain
+type redirect struct {
+	StatusCode int
+	Status     string
+	Location   string
+}
+
+// Redirected returns whether the link redirected at least once
+func (r result) Redirected() bool {
+	return len(r.Redirects) > 0
+}
+
+// PermanentlyRedirected returns whether any hop of the redirect chain was permanent
+func (r result) PermanentlyRedirected() bool {
+	for _, hop := range r.Redirects {
+		if hop.StatusCode == http.StatusMovedPermanently || hop.StatusCode == http.StatusPermanentRedirect {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Failure classes of results that aren't simply 200 OK. Errors are classed by
+// their error category.
+const (
+	classRedirect   = "redirect"
+	classNotFound   = "404"
+	classClient     = "4xx"
+	classServer     = "5xx"
+	classUnfollowed = "3xx"
+	classOther      = "other"
+)
+
+// Class returns the failure class of the result: its error category, the
+// class of its non-OK status, redirect for an OK status reached by
+// redirecting, or an empty string for an OK status
+func (r result) Class() string {
+	switch {
+	case r.Err != nil:
+		return r.Category
+	case isOKStatus(r.StatusCode) && r.Redirected():
+		return classRedirect
+	case isOKStatus(r.StatusCode):
+		return ""
+	case r.StatusCode == http.StatusNotFound:
+		return classNotFound
+	case r.StatusCode >= 500:
+		return classServer
+	case r.StatusCode >= 400:
+		return classClient
+	case r.StatusCode >= 300:
+		return classUnfollowed
+	default:
+		return classOther
+	}
+}
+
+// maxRedirects is the most redirects followed before giving up on a link
+const maxRedirects = 10
+
+var (
+	errRedirectLoop     = errors.New("redirect loop")
+	errTooManyRedirects = fmt.Errorf("stopped after %v redirects", maxRedirects)
+)
+
+// client returns an HTTP client with the configured timeout for each request.
+// It doesn't follow redirects itself, so each hop can be recorded.
+func (cfg checkConfig) client() *http.Client {
+	return &http.Client{
+		Timeout: cfg.timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// methodAuto requests with HEAD, falling back to GET when HEAD looks unreliable
+const methodAuto = "auto"
+
+// headFallbackStatuses are the HEAD response statuses that servers commonly
+// return when only GET is supported
+var headFallbackStatuses = map[int]bool{
+	http.StatusForbidden:        true,
+	http.StatusNotFound:         true,
+	http.StatusMethodNotAllowed: true,
+	http.StatusNotImplemented:   true,
+}
+
+// maxDiscardedBody is the most of a response body read before closing it
+const maxDiscardedBody = 64 * 1024
+
+// statusProducer checks links with a bounded pool of workers, sending each
+// result as soon as its check completes. Links are queued by what -per-host
+// limits, so that links waiting for a busy host don't take the workers other
+// hosts could use. Once ctx is done, the remaining links fail without being
+// requested.
+func statusProducer(ctx context.Context, links []link, cfg checkConfig) <-chan result {
+	// every result fits, so a slow printer never holds up the checks
+	dest := make(chan result, len(links))
+
+	workers := cfg.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	slots := make(chan struct{}, workers)
+
+	wg := sync.WaitGroup{}
+	for _, queue := range queueLinks(links, cfg) {
+		jobs := make(chan link, len(queue))
+		for _, l := range queue {
+			jobs <- l
+		}
+		close(jobs)
+
+		// a queue is worked by as many checks as may run at once for its host
+		checkers := cfg.perHost
+		if checkers < 1 || checkers > workers {
+			checkers = workers
+		}
+
+		for i := 0; i < checkers && i < len(queue); i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for l := range jobs {
+					slots <- struct{}{}
+					r := checkStatus(ctx, l, cfg)
+					<-slots
+
+					dest <- r
+				}
+			}()
+		}
+	}
+
+	go func() {
+		wg.Wait()
+		close(dest)
+	}()
+
+	return dest
+}
+
+// queueLinks splits links, keeping their order, into a queue for each key
+// -per-host limits, or a single queue when there is no limit
+func queueLinks(links []link, cfg checkConfig) [][]link {
+	if cfg.perHost < 1 {
+		return [][]link{links}
+	}
+
+	var queues [][]link
+	index := map[string]int{}
+
+	for _, l := range links {
+		key := limitKey(l.URL, cfg.limitBy)
+
+		i, ok := index[key]
+		if !ok {
+			i = len(queues)
+			index[key] = i
+			queues = append(queues, nil)
+		}
+
+		queues[i] = append(queues[i], l)
+	}
+
+	return queues
+}
+
+func checkStatus(ctx context.Context, l link, cfg checkConfig) result {
+	start := time.Now()
+	reqURL := requestURL(l.URL)
+
+	auto := cfg.method == methodAuto || len(cfg.method) == 0
+
+	method := strings.ToUpper(cfg.method)
+	if auto {
+		method = http.MethodHead
+	}
+
+	resp, redirects, err := follow(ctx, cfg, method, reqURL)
+	if err == nil && auto && headFallbackStatuses[resp.StatusCode] {
+		discard(resp)
+
+		method = http.MethodGet
+		resp, redirects, err = follow(ctx, cfg, method, reqURL)
+	}
+
+	r := result{link: l, RequestURL: reqURL, Method: method, Redirects: redirects, Duration: time.Since(start)}
+	if len(redirects) > 0 {
+		r.FinalURL = redirects[len(redirects)-1].Location
+	}
+
+	if err != nil {
+		r.Err = err
+		r.Category, r.Detail = classifyError(err)
+		return r
+	}
+	discard(resp)
+
+	r.Status = resp.Status
+	r.StatusCode = resp.StatusCode
+	return r
+}
+
+// follow requests reqURL, following and recording each redirect until a
+// non-redirect response, a loop or maxRedirects hops
+func follow(ctx context.Context, cfg checkConfig, method, reqURL string) (*http.Response, []redirect, error) {
+	var redirects []redirect
+	visited := map[string]bool{}
+
+	for {
+		visited[reqURL] = true
+
+		resp, err := requestWithRetries(ctx, cfg, method, reqURL)
+		if err != nil {
+			return nil, redirects, err
+		}
+
+		if !isRedirect(resp.StatusCode) {
+			return resp, redirects, nil
+		}
+
+		// a redirect without a Location is as far as it can be followed
+		location, err := resp.Location()
+		if err != nil {
+			return resp, redirects, nil
+		}
+		discard(resp)
+
+		reqURL = location.String()
+		redirects = append(redirects, redirect{StatusCode: resp.StatusCode, Status: resp.Status, Location: reqURL})
+
+		if visited[reqURL] {
+			return nil, redirects, errRedirectLoop
+		}
+
+		if len(redirects) >= maxRedirects {
+			return nil, redirects, errTooManyRedirects
+		}
+	}
+}
+
+// discard reads only enough of a response body to let the connection be
+// reused for small responses, without downloading large ones, then closes it
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDiscardedBody))
+	resp.Body.Close()
+}
+
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestURL returns the URL to request for an extracted URL, treating a URL
+// without an 'http' prefix as a URN, with an internationalized host in its
+// punycode form
+func requestURL(rawURL string) string {
+	if !strings.HasPrefix(rawURL, "http") {
+		rawURL = fmt.Sprintf("http://%v", rawURL)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	ascii, ok := tld.ToASCII(u.Hostname())
+	if !ok || ascii == strings.ToLower(u.Hostname()) {
+		return rawURL
+	}
+
+	if port := u.Port(); len(port) > 0 {
+		ascii += ":" + port
+	}
+	u.Host = ascii
+
+	return u.String()
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Host)
+}
+
+// limitKey returns what concurrent checks of rawURL are limited by: its host,
+// or its registrable domain when limitBy is "domain"
+func limitKey(rawURL, limitBy string) string {
+	if limitBy != "domain" {
+		return hostOf(requestURL(rawURL))
+	}
+
+	return domainOf(rawURL)
+}
+
+// domainOf returns the registrable domain of the host of rawURL, or the
+// address itself for an IP address, which has no domain
+func domainOf(rawURL string) string {
+	u, err := url.Parse(requestURL(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	if addr, ok := hosts.ParseIP(u.Hostname()); ok {
+		return addr.String()
+	}
+
+	return tld.RegistrableDomain(u.Hostname())
+}
+
+// mixedScripts returns the scripts mixed in the host of rawURL, or nil when
+// it doesn't mix scripts
+func mixedScripts(rawURL string) []string {
+	u, err := url.Parse(requestURL(rawURL))
+	if err != nil {
+		return nil
+	}
+
+	return tld.MixedScripts(u.Hostname())
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStatusProducerLimitsConcurrency(t *testing.T) {
+	examples := []struct {
+		cfg      checkConfig
+		expected int
+	}{
+		{checkConfig{concurrency: 3}, 3},
+		{checkConfig{concurrency: 10, perHost: 2}, 2},
+	}
+
+	for _, example := range examples {
+		server, maxInFlight := newInFlightServer()
+
+		var links []link
+		for i := 0; i < 12; i++ {
+			links = append(links, link{URL: fmt.Sprintf("%v/%v", server.URL, i)})
+		}
+
+		checked := 0
+		for r := range statusProducer(context.Background(), links, example.cfg) {
+			if r.Err != nil || r.StatusCode != 200 {
+				t.Errorf("Expected 200 OK for %v, but got %v %v", r.URL, r.StatusCode, r.Err)
+			}
+			checked++
+		}
+		server.Close()
+
+		if checked != len(links) {
+			t.Errorf("Expected %v results, but got %v", len(links), checked)
+		}
+
+		if actual := maxInFlight(); actual > example.expected {
+			t.Errorf("Expected at most %v requests at once with %+v, but got %v", example.expected, example.cfg, actual)
+		}
+	}
+}
+
+func TestStatusProducerQueuesByHost(t *testing.T) {
+	slow, _ := newInFlightServer()
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	var links []link
+	for i := 0; i < 6; i++ {
+		links = append(links, link{URL: fmt.Sprintf("%v/%v", slow.URL, i)})
+	}
+	links = append(links, link{URL: fast.URL + "/"})
+
+	// the slow host can only use one of the workers, leaving the other free
+	var order []string
+	for r := range statusProducer(context.Background(), links, checkConfig{concurrency: 2, perHost: 1}) {
+		order = append(order, r.URL)
+	}
+
+	if len(order) != len(links) || order[0] != fast.URL+"/" {
+		t.Errorf("Expected %v to be checked while the slow host is busy, but got %v", fast.URL, order)
+	}
+}
+
+// newInFlightServer returns a slow server and a func reporting the most
+// requests it handled at once
+func newInFlightServer() (*httptest.Server, func() int) {
+	mu := sync.Mutex{}
+	inFlight, maxInFlight := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return maxInFlight
+	}
+}
+
+func TestCheckStatusMethodFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/no-head" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/teapot" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer server.Close()
+
+	examples := []struct {
+		path       string
+		method     string
+		statusCode int
+		usedMethod string
+	}{
+		{"/ok", "auto", 200, http.MethodHead},
+		{"/no-head", "auto", 200, http.MethodGet},
+		{"/no-head", "head", 405, http.MethodHead},
+		{"/no-head", "get", 200, http.MethodGet},
+		{"/missing", "auto", 404, http.MethodGet},
+		{"/teapot", "auto", 418, http.MethodHead},
+	}
+
+	for _, example := range examples {
+		r := checkStatus(context.Background(), link{URL: server.URL + example.path}, checkConfig{method: example.method})
+
+		if r.StatusCode != example.statusCode || r.Method != example.usedMethod {
+			t.Errorf("Expected %v with -method %v to be %v by %v, but got %v by %v", example.path, example.method, example.statusCode, example.usedMethod, r.StatusCode, r.Method)
+		}
+	}
+}
+
+func TestCheckStatusRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/older", http.StatusMovedPermanently))
+	mux.Handle("/older", http.RedirectHandler("/new", http.StatusFound))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/temporary", http.RedirectHandler("/new", http.StatusTemporaryRedirect))
+	mux.Handle("/loop-a", http.RedirectHandler("/loop-b", http.StatusFound))
+	mux.Handle("/loop-b", http.RedirectHandler("/loop-a", http.StatusFound))
+	mux.HandleFunc("/endless/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := checkStatus(context.Background(), link{URL: server.URL + "/old"}, checkConfig{})
+	if r.Err != nil || r.StatusCode != 200 || r.FinalURL != server.URL+"/new" {
+		t.Errorf("Expected /old to redirect to 200 OK at /new, but got %v %v at %v", r.StatusCode, r.Err, r.FinalURL)
+	}
+
+	expected := []redirect{
+		{StatusCode: 301, Status: "301 Moved Permanently", Location: server.URL + "/older"},
+		{StatusCode: 302, Status: "302 Found", Location: server.URL + "/new"},
+	}
+	if len(r.Redirects) != len(expected) || r.Redirects[0] != expected[0] || r.Redirects[1] != expected[1] {
+		t.Errorf("Expected redirect chain %v, but got %v", expected, r.Redirects)
+	}
+
+	if !r.PermanentlyRedirected() {
+		t.Errorf("Expected a chain including a 301 to be permanent")
+	}
+
+	if r := checkStatus(context.Background(), link{URL: server.URL + "/temporary"}, checkConfig{}); !r.Redirected() || r.PermanentlyRedirected() {
+		t.Errorf("Expected /temporary to redirect temporarily, but got %v", r.Redirects)
+	}
+
+	if r := checkStatus(context.Background(), link{URL: server.URL + "/new"}, checkConfig{}); r.Redirected() {
+		t.Errorf("Expected /new not to redirect, but got %v", r.Redirects)
+	}
+
+	if r := checkStatus(context.Background(), link{URL: server.URL + "/loop-a"}, checkConfig{}); r.Err != errRedirectLoop || len(r.Redirects) != 2 {
+		t.Errorf("Expected /loop-a to be a redirect loop after 2 hops, but got %v after %v", r.Err, r.Redirects)
+	}
+
+	if r := checkStatus(context.Background(), link{URL: server.URL + "/endless/"}, checkConfig{}); r.Err != errTooManyRedirects || len(r.Redirects) != maxRedirects {
+		t.Errorf("Expected /endless/ to stop after %v redirects, but got %v after %v", maxRedirects, r.Err, len(r.Redirects))
+	}
+}
+
+func TestRequestURL(t *testing.T) {
+	examples := map[string]string{
+		"http://example.com/a?b":             "http://example.com/a?b",
+		"example.com/a":                      "http://example.com/a",
+		"http://Example.COM/":                "http://Example.COM/",
+		"https://bücher.example:8443/x":      "https://xn--bcher-kva.example:8443/x",
+		"hotel.嘉里大酒店/rooms":                  "http://hotel.xn--w4r85el8fhu5dnra/rooms",
+		"http://hotel.xn--w4r85el8fhu5dnra/": "http://hotel.xn--w4r85el8fhu5dnra/",
+	}
+
+	for rawURL, expected := range examples {
+		if actual := requestURL(rawURL); actual != expected {
+			t.Errorf("Expected to request %v for %v, but got %v", expected, rawURL, actual)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Error categories a failed check is classified into
+const (
+	categoryDNS        = "dns"
+	categoryConnect    = "connect"
+	categoryTLS        = "tls"
+	categoryTimeout    = "timeout"
+	categoryProtocol   = "protocol"
+	categoryInvalidURL = "invalid-url"
+)
+
+// classifyError returns the category of an error from checking a link, with a
+// short message describing it
+func classifyError(err error) (string, string) {
+	var (
+		dnsErr      *net.DNSError
+		opErr       *net.OpError
+		urlErr      *url.Error
+		unknownCA   x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+	)
+
+	switch {
+	case err == errRedirectLoop || err == errTooManyRedirects:
+		return categoryProtocol, err.Error()
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsNotFound {
+			return categoryDNS, "no such host " + dnsErr.Name
+		}
+
+		return categoryDNS, dnsErr.Err + " looking up " + dnsErr.Name
+	case errors.As(err, &unknownCA):
+		return categoryTLS, "certificate signed by unknown authority"
+	case errors.As(err, &hostnameErr):
+		return categoryTLS, "certificate is not valid for " + hostnameErr.Host
+	case errors.As(err, &invalidCert):
+		return categoryTLS, invalidCert.Error()
+	case errors.As(err, &verifyErr):
+		return categoryTLS, verifyErr.Err.Error()
+	case errors.As(err, &recordErr):
+		return categoryTLS, "server did not respond with TLS"
+	case errors.As(err, &alertErr):
+		return categoryTLS, alertErr.Error()
+	case isTimeout(err):
+		return categoryTimeout, "timed out"
+	case errors.As(err, &urlErr) && urlErr.Op == "parse":
+		return categoryInvalidURL, urlErr.Err.Error()
+	case strings.Contains(err.Error(), "unsupported protocol scheme"),
+		strings.Contains(err.Error(), "no Host in request URL"),
+		strings.Contains(err.Error(), "invalid URL"):
+		return categoryInvalidURL, innermost(err).Error()
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return categoryConnect, "connection refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return categoryConnect, "connection reset"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return categoryConnect, "host unreachable"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return categoryConnect, innermost(err).Error()
+	default:
+		return categoryProtocol, innermost(err).Error()
+	}
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// innermost unwraps err as far as it goes, dropping the request details that
+// wrapping errors add
+func innermost(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+
+		err = next
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slowServer.Close()
+
+	garbage := listen(t, func(conn net.Conn) {
+		conn.Write([]byte("not http\r\n\r\n"))
+		conn.Close()
+	})
+	defer garbage.Close()
+
+	refused := listen(t, func(conn net.Conn) {})
+	refusedURL := "http://" + refused.Addr().String()
+	refused.Close()
+
+	examples := []struct {
+		url      string
+		category string
+	}{
+		{"http://example.invalid", categoryDNS},
+		{refusedURL, categoryConnect},
+		{tlsServer.URL, categoryTLS},
+		{slowServer.URL, categoryTimeout},
+		{"http://" + garbage.Addr().String(), categoryProtocol},
+		{"http://exa mple.com", categoryInvalidURL},
+		{"http:///no-host", categoryInvalidURL},
+	}
+
+	for _, example := range examples {
+		r := checkStatus(context.Background(), link{URL: example.url}, checkConfig{timeout: 500 * time.Millisecond, method: "head"})
+
+		if r.Err == nil || r.Category != example.category || len(r.Detail) == 0 {
+			t.Errorf("Expected %v to fail with a %v error, but got %v '%v' (%v)", example.url, example.category, r.Category, r.Detail, r.Err)
+		}
+	}
+}
+
+func TestClassifyRedirectErrors(t *testing.T) {
+	for _, err := range []error{errRedirectLoop, errTooManyRedirects} {
+		if category, detail := classifyError(err); category != categoryProtocol || detail != err.Error() {
+			t.Errorf("Expected %v to be a protocol error, but got %v '%v'", err, category, detail)
+		}
+	}
+}
+
+// listen accepts connections on a local port, handing each to handle
+func listen(t *testing.T, handle func(net.Conn)) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go handle(conn)
+		}
+	}()
+
+	return l
+}
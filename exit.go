@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// errorLog prints errors that don't stop urlstat, such as unreadable files,
+// remembering that they happened for the exit code
+type errorLog struct {
+	w io.Writer
+
+	mu    sync.Mutex
+	count int
+}
+
+// add prints err, if it isn't nil
+func (l *errorLog) add(err error) {
+	if err == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.count++
+	fmt.Fprintf(l.w, "Error '%v'\n", err)
+}
+
+// exitCode returns exitInternal if any errors were logged, otherwise code
+func (l *errorLog) exitCode(code int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count > 0 {
+		return exitInternal
+	}
+
+	return code
+}
+
+// errWriter remembers the first error writing to w and skips the writes after
+// it, so that printers can check for an error once rather than on every write
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
+
+// failureTracker counts the results that are failures as they pass through
+type failureTracker struct {
+	failures int64
+}
+
+// track passes on every result from src, counting those isFailure is true for
+func (f *failureTracker) track(src <-chan result, isFailure func(result) bool) <-chan result {
+	dest := make(chan result, 100)
+
+	go func() {
+		for r := range src {
+			if isFailure(r) {
+				atomic.AddInt64(&f.failures, 1)
+			}
+
+			dest <- r
+		}
+		close(dest)
+	}()
+
+	return dest
+}
+
+func (f *failureTracker) count() int64 {
+	return atomic.LoadInt64(&f.failures)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmks/urlstat/options"
+)
+
+func TestErrorLog(t *testing.T) {
+	out := bytes.Buffer{}
+	errLog := &errorLog{w: &out}
+
+	errLog.add(nil)
+	if code := errLog.exitCode(exitBroken); code != exitBroken || out.Len() > 0 {
+		t.Errorf("Expected nil errors to be ignored, but got exit code %v and '%v'", code, out.String())
+	}
+
+	errLog.add(errors.New("open missing.md: no such file or directory"))
+	if code := errLog.exitCode(exitOK); code != exitInternal {
+		t.Errorf("Expected exit code %v after an error, but got %v", exitInternal, code)
+	}
+
+	if expected := "Error 'open missing.md: no such file or directory'\n"; out.String() != expected {
+		t.Errorf("Expected '%v' to be printed, but got '%v'", expected, out.String())
+	}
+}
+
+func TestFailureTracker(t *testing.T) {
+	src := make(chan result, 3)
+	src <- result{StatusCode: 200}
+	src <- result{StatusCode: 404}
+	src <- result{StatusCode: 500}
+	close(src)
+
+	failures := failureTracker{}
+	passed := 0
+	for range failures.track(src, func(r result) bool { return r.StatusCode == 404 }) {
+		passed++
+	}
+
+	if passed != 3 || failures.count() != 1 {
+		t.Errorf("Expected all 3 results to pass through with 1 failure, but got %v with %v", passed, failures.count())
+	}
+}
+
+// failingWriter fails every write, like stdout to a closed pipe
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestRunExitCodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	ok := filepath.Join(dir, "ok.md")
+	writeFile(t, ok, "See "+server.URL+"/ok\n")
+	broken := filepath.Join(dir, "broken.md")
+	writeFile(t, broken, "See "+server.URL+"/missing\n")
+	missing := filepath.Join(dir, "missing.md")
+
+	examples := []struct {
+		args     string
+		stdout   io.Writer
+		expected int
+	}{
+		{ok, &bytes.Buffer{}, exitOK},
+		{"-stream " + ok, &bytes.Buffer{}, exitOK},
+		{"-list " + ok, &bytes.Buffer{}, exitOK},
+		{broken, &bytes.Buffer{}, exitBroken},
+		{"-fail-on 5xx " + broken, &bytes.Buffer{}, exitOK},
+		{"-concurrency 0 " + ok, &bytes.Buffer{}, exitUsage},
+		{"-no-such-flag " + ok, &bytes.Buffer{}, exitUsage},
+		{"-list -format junit " + ok, &bytes.Buffer{}, exitUsage},
+		{"-list -format sarif " + ok, &bytes.Buffer{}, exitUsage},
+		{"-list -format ndjson " + ok, &bytes.Buffer{}, exitOK},
+		{"-tld-file " + missing + " " + ok, &bytes.Buffer{}, exitUsage},
+		{missing, &bytes.Buffer{}, exitInternal},
+		{ok, failingWriter{}, exitInternal},
+		{"-stream " + ok, failingWriter{}, exitInternal},
+		{"-list " + ok, failingWriter{}, exitInternal},
+		{"-format json " + ok, failingWriter{}, exitInternal},
+	}
+
+	for _, example := range examples {
+		opts := options.ParseArgs(strings.Fields(example.args))
+
+		stderr := bytes.Buffer{}
+
+		if code := run(opts, example.stdout, &stderr); code != example.expected {
+			t.Errorf("Expected '%v' to exit %v, but got %v: %v", example.args, example.expected, code, stderr.String())
+		}
+
+		if example.expected == exitUsage && !strings.Contains(stderr.String(), "Usage of URLstat") && !strings.Contains(stderr.String(), "Invalid -tld-file") {
+			t.Errorf("Expected '%v' to print the problem to stderr, but got '%v'", example.args, stderr.String())
+		}
+	}
+}
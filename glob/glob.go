@@ -0,0 +1,64 @@
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match returns whether the slash separated name matches pattern.
+// Besides the syntax of path.Match, a "**" segment matches any number of
+// segments, and a pattern without a slash matches the last segment of name.
+func Match(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		return matchSegment(pattern, path.Base(name))
+	}
+
+	return matchSegments(split(pattern), split(name))
+}
+
+// IsValid returns whether pattern is well formed
+func IsValid(pattern string) bool {
+	for _, segment := range split(pattern) {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 || !matchSegment(pattern[0], name[0]) {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+func matchSegment(pattern, name string) bool {
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+func split(s string) []string {
+	s = strings.Trim(s, "/")
+	if len(s) == 0 {
+		return nil
+	}
+
+	return strings.Split(s, "/")
+}
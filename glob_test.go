@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jmks/urlstat/glob"
+)
+
+func TestGlobMatch(t *testing.T) {
+	examples := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide/install.md", true},
+		{"*.md", "docs/guide/install.html", false},
+		{"docs/*.md", "docs/index.md", true},
+		{"docs/*.md", "docs/guide/install.md", false},
+		{"docs/**/*.md", "docs/index.md", true},
+		{"docs/**/*.md", "docs/guide/install.md", true},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/github.com/x/README.md", true},
+		{"vendor/**", "src/vendor/README.md", false},
+		{"**/vendor/**", "src/vendor/README.md", true},
+		{"**/testdata", "a/b/testdata", true},
+		{"/docs/?.md", "docs/a.md", true},
+		{"docs/[a-c].md", "docs/d.md", false},
+	}
+
+	for _, example := range examples {
+		if actual := glob.Match(example.pattern, example.name); actual != example.expected {
+			t.Errorf("Expected '%v' matching '%v' to be %v, but got %v", example.pattern, example.name, example.expected, actual)
+		}
+	}
+}
+
+func TestGlobIsValid(t *testing.T) {
+	examples := map[string]bool{
+		"**/*.md":  true,
+		"docs/[a-": false,
+		"a\\":      false,
+	}
+
+	for pattern, expected := range examples {
+		if actual := glob.IsValid(pattern); actual != expected {
+			t.Errorf("Expected '%v' to be valid: %v, but got %v", pattern, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package hosts
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/jmks/urlstat/tld"
+)
+
+// Host is the host of a URL, split into its hostname and port
+type Host struct {
+	Name string // e.g. example.com, 192.168.1.10 or ::1, without brackets
+	Port string // empty when there isn't one
+	IP   netip.Addr
+}
+
+// IsIP returns whether the hostname is an IP address
+func (h Host) IsIP() bool {
+	return h.IP.IsValid()
+}
+
+var (
+	errEmptyHost   = errors.New("empty hostname")
+	errInvalidIPv6 = errors.New("IPv6 address must be in brackets")
+	errInvalidPort = errors.New("invalid port")
+)
+
+// Split parses the host of a URL, with an optional port, like example.com:8080
+// or [::1]:9000. IPv6 addresses must be in brackets and ports must be from 1
+// to 65535.
+func Split(hostport string) (Host, error) {
+	var h Host
+	var port string
+	hasPort := false
+
+	if strings.HasPrefix(hostport, "[") {
+		end := strings.Index(hostport, "]")
+		if end == -1 {
+			return Host{}, errInvalidIPv6
+		}
+
+		addr, err := netip.ParseAddr(hostport[1:end])
+		if err != nil || !addr.Is6() {
+			return Host{}, errInvalidIPv6
+		}
+
+		h.Name, h.IP = hostport[1:end], addr
+
+		rest := hostport[end+1:]
+		if len(rest) > 0 && !strings.HasPrefix(rest, ":") {
+			return Host{}, errInvalidPort
+		}
+		port, hasPort = strings.CutPrefix(rest, ":")
+	} else {
+		if strings.Count(hostport, ":") > 1 {
+			return Host{}, errInvalidIPv6
+		}
+
+		h.Name, port, hasPort = strings.Cut(hostport, ":")
+
+		if addr, err := netip.ParseAddr(h.Name); err == nil {
+			h.IP = addr
+		}
+	}
+
+	if len(h.Name) == 0 {
+		return Host{}, errEmptyHost
+	}
+
+	// an empty port, like example.com:, is the default port
+	if hasPort && len(port) > 0 && !isPort(port) {
+		return Host{}, errInvalidPort
+	}
+	h.Port = port
+
+	return h, nil
+}
+
+// isPort returns whether s is a decimal number from 1 to 65535
+func isPort(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+// loopbackNames are the hostnames that always refer to the local machine
+var loopbackNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"localhost6":            true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+}
+
+// IsLoopbackName returns whether hostname is exactly a name for the local
+// machine, like localhost, ignoring case and a trailing dot
+func IsLoopbackName(hostname string) bool {
+	return loopbackNames[strings.ToLower(strings.TrimSuffix(hostname, "."))]
+}
+
+// Known returns whether the host of a URL, with any port, should be checked:
+// a loopback name or IP address in a range that isn't excluded, or a host
+// known by the tld package
+func Known(hostport string) bool {
+	h, err := Split(hostport)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case h.IsIP():
+		return IsAllowedIP(h.IP)
+	case IsLoopbackName(h.Name):
+		return !excluded[RangeLoopback]
+	default:
+		return tld.IsKnownHost(h.Name)
+	}
+}
+
+// IP ranges that can be excluded
+const (
+	RangePrivate   = "private"    // 10/8, 172.16/12, 192.168/16 and fc00::/7
+	RangeLoopback  = "loopback"   // 127/8 and ::1
+	RangeLinkLocal = "link-local" // 169.254/16 and fe80::/10
+)
+
+// Ranges are the IP ranges that can be excluded
+var Ranges = []string{RangePrivate, RangeLoopback, RangeLinkLocal}
+
+var excluded = map[string]bool{}
+
+// ExcludeIPRanges rejects IP literals in the ranges, which may include "all".
+// It isn't safe to call while hosts are being checked.
+func ExcludeIPRanges(ranges []string) {
+	excluded = map[string]bool{}
+
+	for _, r := range ranges {
+		if r == "all" {
+			for _, all := range Ranges {
+				excluded[all] = true
+			}
+		}
+
+		excluded[r] = true
+	}
+}
+
+// ParseIP parses hostname as an IPv4 literal in dotted decimal, or an IPv6
+// literal with or without its brackets
+func ParseIP(hostname string) (netip.Addr, bool) {
+	if strings.HasPrefix(hostname, "[") && strings.HasSuffix(hostname, "]") {
+		hostname = hostname[1 : len(hostname)-1]
+	}
+
+	addr, err := netip.ParseAddr(hostname)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr, true
+}
+
+// RangeOf returns the range addr is in, or an empty string for a public address
+func RangeOf(addr netip.Addr) string {
+	addr = addr.Unmap()
+
+	switch {
+	case addr.IsLoopback():
+		return RangeLoopback
+	case addr.IsPrivate():
+		return RangePrivate
+	case addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast():
+		return RangeLinkLocal
+	default:
+		return ""
+	}
+}
+
+// IsAllowedIP returns whether addr isn't in an excluded range
+func IsAllowedIP(addr netip.Addr) bool {
+	return !excluded[RangeOf(addr)]
+}
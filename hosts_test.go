@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmks/urlstat/hosts"
+)
+
+func TestParseIP(t *testing.T) {
+	examples := map[string]string{
+		"192.168.1.10":       "private",
+		"10.0.0.1":           "private",
+		"172.16.5.4":         "private",
+		"172.32.0.1":         "",
+		"8.8.8.8":            "",
+		"127.0.0.1":          "loopback",
+		"169.254.169.254":    "link-local",
+		"[::1]":              "loopback",
+		"::1":                "loopback",
+		"[2001:db8::1]":      "",
+		"[fd00::1]":          "private",
+		"[fe80::1%eth0]":     "link-local",
+		"[::ffff:10.0.0.1]":  "private",
+		"[2606:4700::1111]":  "",
+		"[::ffff:127.0.0.1]": "loopback",
+	}
+
+	for hostname, expected := range examples {
+		addr, ok := hosts.ParseIP(hostname)
+		if !ok {
+			t.Errorf("Expected %v to be an IP address", hostname)
+			continue
+		}
+
+		if actual := hosts.RangeOf(addr); actual != expected {
+			t.Errorf("Expected %v to be in range '%v', but got '%v'", hostname, expected, actual)
+		}
+	}
+
+	for _, hostname := range []string{"", "1.2.3", "1.2.3.4.5", "256.1.1.1", "01.2.3.4", "example.com", "[1.2.3.4", "::1]", "1.2.3.4:80"} {
+		if _, ok := hosts.ParseIP(hostname); ok {
+			t.Errorf("Expected %v not to be an IP address", hostname)
+		}
+	}
+}
+
+func TestExtractIPURLs(t *testing.T) {
+	defer hosts.ExcludeIPRanges(nil)
+
+	source := "http://192.168.1.10:8080/health http://[::1]:9000/ https://8.8.8.8/ 10.0.0.1/status v1.2.3.4 http://169.254.169.254/latest http://999.1.1.1/ http://[::1/"
+
+	expected := []string{"http://192.168.1.10:8080/health", "http://[::1]:9000/", "https://8.8.8.8/", "10.0.0.1/status", "http://169.254.169.254/latest"}
+	if urls := matchedURLs(extractURLs(strings.NewReader(source))); !stringSlicesEqual(urls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, urls)
+	}
+
+	hosts.ExcludeIPRanges([]string{"private", "link-local"})
+
+	expected = []string{"http://[::1]:9000/", "https://8.8.8.8/"}
+	if urls := matchedURLs(extractURLs(strings.NewReader(source))); !stringSlicesEqual(urls, expected) {
+		t.Errorf("Expected private and link-local addresses to be skipped, but got %v", urls)
+	}
+
+	hosts.ExcludeIPRanges([]string{"all"})
+
+	expected = []string{"https://8.8.8.8/"}
+	if urls := matchedURLs(extractURLs(strings.NewReader(source))); !stringSlicesEqual(urls, expected) {
+		t.Errorf("Expected only public addresses, but got %v", urls)
+	}
+}
+
+func TestSplitHost(t *testing.T) {
+	examples := []struct {
+		hostport string
+		name     string
+		port     string
+		ip       bool
+		valid    bool
+	}{
+		{"example.com", "example.com", "", false, true},
+		{"example.com:8080", "example.com", "8080", false, true},
+		{"example.com:", "example.com", "", false, true},
+		{"localhost:9000", "localhost", "9000", false, true},
+		{"192.168.1.10:8080", "192.168.1.10", "8080", true, true},
+		{"[::1]", "::1", "", true, true},
+		{"[::1]:9000", "::1", "9000", true, true},
+		{"[fe80::1%25eth0]:80", "fe80::1%25eth0", "80", true, true},
+		{"", "", "", false, false},
+		{":8080", "", "", false, false},
+		{"::1", "", "", false, false},
+		{"[::1", "", "", false, false},
+		{"[127.0.0.1]", "", "", false, false},
+		{"[::1]9000", "", "", false, false},
+		{"example.com:0", "", "", false, false},
+		{"example.com:65536", "", "", false, false},
+		{"example.com:+80", "", "", false, false},
+		{"example.com:http", "", "", false, false},
+		{"example.com:80:80", "", "", false, false},
+	}
+
+	for _, example := range examples {
+		h, err := hosts.Split(example.hostport)
+
+		if (err == nil) != example.valid {
+			t.Errorf("Expected %q to be valid %v, but got %v", example.hostport, example.valid, err)
+			continue
+		}
+
+		if h.Name != example.name || h.Port != example.port || h.IsIP() != example.ip {
+			t.Errorf("Expected %q to split into %q, %q (IP %v), but got %+v", example.hostport, example.name, example.port, example.ip, h)
+		}
+	}
+}
+
+func TestKnownHost(t *testing.T) {
+	defer hosts.ExcludeIPRanges(nil)
+
+	examples := []struct {
+		hostport string
+		known    bool
+	}{
+		{"localhost", true},
+		{"localhost:8080", true},
+		{"LOCALHOST", true},
+		{"localhost.", true},
+		{"localhost.localdomain", true},
+		{"ip6-localhost:631", true},
+		{"localhostfoo", false},
+		{"localhost.faketld", false},
+		{"localhost.evil.com", true}, // a host of evil.com, not loopback
+		{"localhost:99999", false},
+		{"localhost:abc", false},
+		{"example.com", true},
+		{"example.com:8080", true},
+		{"example.faketld:8080", false},
+		{"127.0.0.1:8080", true},
+		{"[::1]:9000", true},
+		{"::1", false},
+		{"jenkins", false},
+	}
+
+	for _, example := range examples {
+		if actual := hosts.Known(example.hostport); actual != example.known {
+			t.Errorf("Expected %q to be known %v, but got %v", example.hostport, example.known, actual)
+		}
+	}
+
+	hosts.ExcludeIPRanges([]string{"loopback"})
+
+	for _, hostport := range []string{"localhost", "localhost:8080", "127.0.0.1", "[::1]:9000"} {
+		if hosts.Known(hostport) {
+			t.Errorf("Expected loopback %q to be excluded", hostport)
+		}
+	}
+
+	if !hosts.IsLoopbackName("Localhost.") || hosts.IsLoopbackName("localhost.evil.com") || hosts.IsLoopbackName("my-localhost") {
+		t.Error("Expected only exact loopback names to be loopback")
+	}
+}
+
+func TestExtractLocalhostURLs(t *testing.T) {
+	source := "http://localhost:9000/200 http://localhostfoo/ http://localhost.fakeTLD/ http://localhost.evil.com/ https://example.com:8443/ http://localhost:99999/"
+	expected := []string{"http://localhost:9000/200", "http://localhost.evil.com/", "https://example.com:8443/"}
+
+	if urls := matchedURLs(extractURLs(strings.NewReader(source))); !stringSlicesEqual(urls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, urls)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// urlAttributes lists, per element, the attributes holding a single URL
+var urlAttributes = map[string][]string{
+	"a":      {"href"},
+	"link":   {"href"},
+	"img":    {"src"},
+	"script": {"src"},
+	"iframe": {"src"},
+	"form":   {"action"},
+}
+
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)`)
+
+// extractHTMLURLs finds URLs in element attributes, meta refreshes and inline
+// styles. Relative links are resolved against the document's <base href>, or
+// base, the URL of the document, or skipped when there is neither.
+func extractHTMLURLs(source io.Reader, base *url.URL) ([]match, error) {
+	var links []location
+
+	found := func(rawURL string, pos position) {
+		links = append(links, location{Line: pos.line, Column: pos.column, Text: rawURL})
+	}
+
+	// a <base href> applies to every link in the document, even those before
+	// it, so links are only resolved once the whole document is read
+	resolved := func() []match {
+		var matches []match
+		for _, loc := range links {
+			if u, ok := resolveURL(loc.Text, base); ok {
+				matches = append(matches, match{URL: u, location: loc})
+			}
+		}
+
+		return matches
+	}
+
+	pos := position{line: 1, column: 1}
+	inStyle := false
+	baseSeen := false
+
+	z := html.NewTokenizer(source)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return resolved(), err
+			}
+
+			return resolved(), nil
+		}
+
+		raw := string(z.Raw())
+		start := pos
+		pos.advance(raw)
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			inStyle = token.Data == "style" && tt == html.StartTagToken
+
+			for _, attr := range token.Attr {
+				attrPos := start.within(raw, attr.Val)
+
+				switch {
+				case token.Data == "base" && attr.Key == "href" && !baseSeen:
+					// only the first <base href> counts
+					base, baseSeen = baseHref(strings.TrimSpace(attr.Val), base), true
+				case isURLAttribute(token.Data, attr.Key):
+					found(strings.TrimSpace(attr.Val), attrPos)
+				case attr.Key == "srcset" && (token.Data == "img" || token.Data == "source"):
+					for _, candidate := range srcsetURLs(attr.Val) {
+						found(candidate, start.within(raw, candidate))
+					}
+				case attr.Key == "content" && token.Data == "meta" && isMetaRefresh(token):
+					if refresh, ok := metaRefreshURL(attr.Val); ok {
+						found(refresh, start.within(raw, refresh))
+					}
+				case attr.Key == "style":
+					for _, styleURL := range cssURLs(attr.Val) {
+						found(styleURL, start.within(raw, styleURL))
+					}
+				}
+			}
+		case html.TextToken:
+			if inStyle {
+				for _, styleURL := range cssURLs(raw) {
+					found(styleURL, start.within(raw, styleURL))
+				}
+			}
+		case html.EndTagToken:
+			inStyle = false
+		}
+	}
+}
+
+// baseHref returns the URL relative links resolve against in a document at
+// doc with a <base href>: href resolved against doc, or href alone when doc is
+// nil. It returns doc when href isn't a usable http or https URL.
+func baseHref(href string, doc *url.URL) *url.URL {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return doc
+	}
+
+	if doc != nil {
+		ref = doc.ResolveReference(ref)
+	}
+
+	if (ref.Scheme != "http" && ref.Scheme != "https") || len(ref.Host) == 0 {
+		return doc
+	}
+
+	return ref
+}
+
+func isURLAttribute(element, attr string) bool {
+	for _, a := range urlAttributes[element] {
+		if a == attr {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isMetaRefresh(token html.Token) bool {
+	for _, attr := range token.Attr {
+		if attr.Key == "http-equiv" && strings.EqualFold(strings.TrimSpace(attr.Val), "refresh") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metaRefreshURL extracts the URL from a meta refresh content like "5; url=http://example.com"
+func metaRefreshURL(content string) (string, bool) {
+	for _, part := range strings.Split(content, ";") {
+		part = strings.TrimSpace(part)
+		if len(part) < 4 || !strings.EqualFold(part[:4], "url=") {
+			continue
+		}
+
+		refresh := strings.Trim(strings.TrimSpace(part[4:]), `'"`)
+		return refresh, len(refresh) > 0
+	}
+
+	return "", false
+}
+
+// srcsetURLs extracts the URL of each candidate in a srcset like "a.png 1x, b.png 2x"
+func srcsetURLs(srcset string) []string {
+	var urls []string
+
+	for _, candidate := range strings.Split(srcset, ",") {
+		if f := strings.Fields(candidate); len(f) > 0 {
+			urls = append(urls, f[0])
+		}
+	}
+
+	return urls
+}
+
+// cssURLs extracts the arguments of every url() in a CSS declaration block
+func cssURLs(css string) []string {
+	var urls []string
+
+	for _, m := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		urls = append(urls, m[1])
+	}
+
+	return urls
+}
+
+// position is a 1-based line and column within a file
+type position struct {
+	line   int
+	column int
+}
+
+func (p *position) advance(s string) {
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i == -1 {
+			p.column += len(s)
+			return
+		}
+
+		p.line++
+		p.column = 1
+		s = s[i+1:]
+	}
+}
+
+// within returns the position of sub in raw, where raw starts at p, or p when
+// sub does not appear verbatim (e.g. it was unescaped from an entity)
+func (p position) within(raw, sub string) position {
+	i := strings.Index(raw, sub)
+	if i == -1 || len(sub) == 0 {
+		return p
+	}
+
+	p.advance(raw[:i])
+	return p
+}
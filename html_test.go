@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmks/urlstat/hosts"
+)
+
+func TestExtractHTMLURLs(t *testing.T) {
+	examples := map[string][]string{
+		`<a href="http://xkcd.com/974/">xkcd</a>`: []string{"http://xkcd.com/974/"},
+		`<img src="https://example.com/a.png" srcset="https://example.com/b.png 2x, https://example.com/c.png 3x">`: []string{"https://example.com/a.png", "https://example.com/b.png", "https://example.com/c.png"},
+		`<link rel="stylesheet" href="//cdn.example.com/site.css">`:                                                 []string{"https://cdn.example.com/site.css"},
+		`<script src="https://example.com/app.js"></script>`:                                                        []string{"https://example.com/app.js"},
+		`<iframe src="https://example.org/embed"></iframe>`:                                                         []string{"https://example.org/embed"},
+		`<form action="https://example.com/search"></form>`:                                                         []string{"https://example.com/search"},
+		`<meta http-equiv="refresh" content="0; url=https://example.com/new">`:                                      []string{"https://example.com/new"},
+		`<div style="background: url('https://example.com/bg.png')"></div>`:                                         []string{"https://example.com/bg.png"},
+		"<style>\nbody { background: url(https://example.com/body.png); }\n</style>":                                []string{"https://example.com/body.png"},
+		// relative, fragment and non-http links are not checked
+		`<a href="/about">about</a> <a href="#top">top</a> <a href="mailto:me@example.com">me</a>`: []string{},
+		`<a href="https://example.com?a=1&amp;b=2">escaped</a>`:                                    []string{"https://example.com?a=1&b=2"},
+		`<meta name="description" content="url=https://example.com/not-a-refresh">`:                []string{},
+	}
+
+	for source, expected := range examples {
+		actual := matchedURLs(extractHTMLURLs(strings.NewReader(source), nil))
+
+		if !stringSlicesEqual(actual, expected) {
+			t.Errorf("Expected %v from '%v', but actually got %v", expected, source, actual)
+		}
+	}
+}
+
+func TestExtractHTMLURLsLocations(t *testing.T) {
+	source := "<html>\n<body>\n  <p>See <a href=\"https://example.com/\">here</a></p>\n  <img alt=\"x\"\n       src=\"https://example.com/x.png\">\n</body>\n</html>"
+	expected := []location{
+		{Line: 3, Column: 19, Text: "https://example.com/"},
+		{Line: 5, Column: 13, Text: "https://example.com/x.png"},
+	}
+
+	matches, err := extractHTMLURLs(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v matches, but got %v", len(expected), matches)
+	}
+
+	for i, m := range matches {
+		if m.location != expected[i] {
+			t.Errorf("Expected %v to be found at %+v, but got %+v", m.URL, expected[i], m.location)
+		}
+	}
+}
+
+func TestExtractorFor(t *testing.T) {
+	source := `xkcd.com <a href="http://xkcd.com/974/">xkcd</a>`
+
+	if actual := matchedURLs(extractorFor("site/index.HTML", extractConfig{})(strings.NewReader(source))); !stringSlicesEqual(actual, []string{"http://xkcd.com/974/"}) {
+		t.Errorf("Expected .html files to use the HTML extractor, but got %v", actual)
+	}
+
+	if actual := matchedURLs(extractorFor("notes.txt", extractConfig{})(strings.NewReader(source))); !stringSlicesEqual(actual, []string{"xkcd.com"}) {
+		t.Errorf("Expected other files to use the text extractor, but got %v", actual)
+	}
+}
+
+func TestExtractRelativeURLs(t *testing.T) {
+	base, _ := url.Parse("https://docs.example.com/v2/")
+	cfg := extractConfig{base: base, root: "site"}
+
+	html := `<a href="../guide/install.html">install</a>
+<a href="/docs/api">api</a>
+<a href="faq.html#top">faq</a>
+<a href="#top">top</a>
+<a href="mailto:docs@example.com">mail</a>
+<img srcset="img/a.png 1x, //cdn.example.com/b.png 2x">
+<a href="https://example.com/">absolute</a>`
+
+	expected := []string{
+		"https://docs.example.com/v2/guide/install.html",
+		"https://docs.example.com/docs/api",
+		"https://docs.example.com/v2/tutorial/faq.html#top",
+		"https://docs.example.com/v2/tutorial/img/a.png",
+		"https://cdn.example.com/b.png",
+		"https://example.com/",
+	}
+
+	if actual := matchedURLs(extractorFor(filepath.Join("site", "tutorial", "index.html"), cfg)(strings.NewReader(html))); !stringSlicesEqual(actual, expected) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+
+	markdown := "See [install](../guide/install.md \"Install\"), [api](/docs/api), [top](#top) and ../prose/links.md\n\n[ref]: setup.md\n"
+
+	expected = []string{
+		"https://docs.example.com/v2/guide/install.md",
+		"https://docs.example.com/docs/api",
+		"https://docs.example.com/v2/tutorial/setup.md",
+	}
+
+	if actual := matchedURLs(extractorFor(filepath.Join("site", "tutorial", "README.md"), cfg)(strings.NewReader(markdown))); !stringSlicesEqual(actual, expected) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+
+	if actual := matchedURLs(extractorFor("elsewhere/index.html", cfg)(strings.NewReader(html))); len(actual) != 2 {
+		t.Errorf("Expected relative links outside the root to be skipped, but got %v", actual)
+	}
+
+	if actual := matchedURLs(extractorFor("-", cfg)(strings.NewReader("../a.html"))); len(actual) != 0 {
+		t.Errorf("Expected relative links in text to be skipped, but got %v", actual)
+	}
+
+	if doc := cfg.documentURL("-"); doc.String() != "https://docs.example.com/v2/" {
+		t.Errorf("Expected stdin to be published at the base URL, but got %v", doc)
+	}
+}
+
+func TestExtractHTMLBaseHref(t *testing.T) {
+	doc, _ := url.Parse("https://docs.example.com/v2/tutorial/index.html")
+
+	examples := []struct {
+		html     string
+		doc      *url.URL
+		expected []string
+	}{
+		{`<a href="a.html">a</a><base href="https://mirror.example.org/docs/"><a href="/b.html">b</a>`, doc,
+			[]string{"https://mirror.example.org/docs/a.html", "https://mirror.example.org/b.html"}},
+		{`<base href="../"><base href="https://mirror.example.org/"><a href="a.html">a</a>`, doc,
+			[]string{"https://docs.example.com/v2/a.html"}},
+		{`<base href="https://mirror.example.org/docs/"><a href="a.html">a</a>`, nil,
+			[]string{"https://mirror.example.org/docs/a.html"}},
+		{`<base href="docs/"><a href="a.html">a</a>`, nil, []string{}},
+		{`<base href="ftp://example.com/"><a href="a.html">a</a>`, doc, []string{"https://docs.example.com/v2/tutorial/a.html"}},
+	}
+
+	for _, example := range examples {
+		if actual := matchedURLs(extractHTMLURLs(strings.NewReader(example.html), example.doc)); !stringSlicesEqual(actual, example.expected) {
+			t.Errorf("Expected %v from '%v', but got %v", example.expected, example.html, actual)
+		}
+	}
+}
+
+func TestExtractRelativeURLsExcludedHosts(t *testing.T) {
+	defer hosts.ExcludeIPRanges(nil)
+	hosts.ExcludeIPRanges([]string{hosts.RangePrivate})
+
+	for _, base := range []string{"http://10.0.0.1/", "https://docs.example.badtld/"} {
+		doc, _ := url.Parse(base)
+
+		if actual := matchedURLs(extractHTMLURLs(strings.NewReader(`<a href="a.html">a</a>`), doc)); len(actual) != 0 {
+			t.Errorf("Expected links relative to %v to be skipped, but got %v", base, actual)
+		}
+
+		if actual := matchedURLs(extractMarkdownURLs(strings.NewReader("[a](a.md)"), false, doc)); len(actual) != 0 {
+			t.Errorf("Expected Markdown links relative to %v to be skipped, but got %v", base, actual)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/jmks/urlstat/glob"
+)
+
+// Filenames are the ignore files read from each directory, in order of precedence
+var Filenames = []string{".gitignore", ".ignore", ".urlstatignore"}
+
+// Rules are patterns in the gitignore format, from ignore files in any number
+// of directories. Later rules take precedence over earlier ones.
+type Rules []rule
+
+type rule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Parse reads the patterns of an ignore file in the directory base, a slash
+// separated path relative to the root rules match from, like a repository
+// root ("" for the root itself)
+func Parse(r io.Reader, base string) (Rules, error) {
+	var rules Rules
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if rule, ok := parseLine(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, scanner.Err()
+}
+
+func parseLine(line, base string) (rule, bool) {
+	line = trimTrailingSpaces(line)
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	r := rule{base: base}
+
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// a slash anywhere but the end anchors the pattern to the ignore file's directory
+	r.anchored = strings.Contains(line, "/")
+	r.pattern = strings.TrimPrefix(line, "/")
+
+	return r, len(r.pattern) > 0 && glob.IsValid(r.pattern)
+}
+
+// trimTrailingSpaces removes trailing spaces, unless they're escaped with a backslash
+func trimTrailingSpaces(line string) string {
+	trimmed := strings.TrimRight(line, " ")
+	if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) {
+		return trimmed[:len(trimmed)-1] + " "
+	}
+
+	return trimmed
+}
+
+// Ignored returns whether the slash separated path, relative to the root
+// rules match from, is ignored. The last rule matching it decides.
+func (rules Rules) Ignored(name string, isDir bool) bool {
+	ignored := false
+
+	for _, r := range rules {
+		if r.matches(name, isDir) {
+			ignored = !r.negate
+		}
+	}
+
+	return ignored
+}
+
+func (r rule) matches(name string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	rel := name
+	if len(r.base) > 0 {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+
+		rel = name[len(r.base)+1:]
+	}
+
+	// a leading slash makes glob match whole paths, even without another slash
+	if r.anchored {
+		return glob.Match("/"+r.pattern, rel)
+	}
+
+	return glob.Match(r.pattern, path.Base(rel))
+}
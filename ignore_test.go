@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmks/urlstat/ignore"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	root, err := ignore.Parse(strings.NewReader(`
+# comments and blank lines are skipped
+
+*.log
+!keep.log
+build/
+/TODO
+docs/generated/**
+\#notes
+trailing\ `), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nested, err := ignore.Parse(strings.NewReader("*.html\n!index.html\n"), "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := append(root, nested...)
+
+	examples := []struct {
+		name     string
+		isDir    bool
+		expected bool
+	}{
+		{"debug.log", false, true},
+		{"logs/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"TODO", false, true},
+		{"docs/TODO", false, false},
+		{"docs/generated/api.md", false, true},
+		{"docs/index.md", false, false},
+		{"#notes", false, true},
+		{"trailing ", false, true},
+		{"site/about.html", false, true},
+		{"site/index.html", false, false},
+		{"about.html", false, false},
+	}
+
+	for _, example := range examples {
+		if actual := rules.Ignored(example.name, example.isDir); actual != example.expected {
+			t.Errorf("Expected %v (dir: %v) to be ignored: %v, but got %v", example.name, example.isDir, example.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+type jsonLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Text   string `json:"text"`
+}
+
+type jsonLink struct {
+	URL       string         `json:"url"`
+	Locations []jsonLocation `json:"locations"`
+}
+
+type jsonRedirect struct {
+	StatusCode int    `json:"status_code"`
+	Status     string `json:"status"`
+	Location   string `json:"location"`
+}
+
+type jsonError struct {
+	Category string `json:"category"`
+	Detail   string `json:"detail"`
+}
+
+type jsonResult struct {
+	URL          string         `json:"url"`
+	RequestURL   string         `json:"request_url"`
+	Method       string         `json:"method"`
+	StatusCode   int            `json:"status_code,omitempty"`
+	Status       string         `json:"status,omitempty"`
+	Error        *jsonError     `json:"error,omitempty"`
+	DurationMS   float64        `json:"duration_ms"`
+	Redirects    []jsonRedirect `json:"redirects,omitempty"`
+	FinalURL     string         `json:"final_url,omitempty"`
+	MixedScripts []string       `json:"mixed_scripts,omitempty"`
+	Locations    []jsonLocation `json:"locations"`
+}
+
+type jsonSummary struct {
+	Total      int `json:"total"`
+	OK         int `json:"ok"`
+	NotOK      int `json:"not_ok"`
+	Errors     int `json:"errors"`
+	Redirected int `json:"redirected"`
+}
+
+type jsonReport struct {
+	Results []jsonResult `json:"results"`
+	Summary jsonSummary  `json:"summary"`
+}
+
+// printJSON waits for every check to complete, then writes a single document
+// of the printable results, ordered by where they were first found, and a
+// summary of every result
+func printJSON(w io.Writer, src <-chan result, printable func(result) bool) error {
+	report := jsonReport{Results: []jsonResult{}}
+
+	var results []result
+	for r := range src {
+		report.Summary.add(r)
+
+		if printable(r) {
+			results = append(results, r)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return locationLess(results[i].Locations[0], results[j].Locations[0])
+	})
+
+	for _, r := range results {
+		report.Results = append(report.Results, newJSONResult(r))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// printNDJSON writes each printable result as a line of JSON as soon as its
+// check completes
+func printNDJSON(w io.Writer, src <-chan result, printable func(result) bool) error {
+	encoder := json.NewEncoder(w)
+
+	for r := range src {
+		if !printable(r) {
+			continue
+		}
+
+		if err := encoder.Encode(newJSONResult(r)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printLinksJSON writes links, without checking their status, as a single document
+func printLinksJSON(w io.Writer, links []link) error {
+	doc := struct {
+		Links []jsonLink `json:"links"`
+	}{Links: []jsonLink{}}
+
+	for _, l := range links {
+		doc.Links = append(doc.Links, newJSONLink(l))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// printLinksNDJSON writes links, without checking their status, as a line of JSON each
+func printLinksNDJSON(w io.Writer, links []link) error {
+	encoder := json.NewEncoder(w)
+
+	for _, l := range links {
+		if err := encoder.Encode(newJSONLink(l)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonSummary) add(r result) {
+	s.Total++
+
+	switch {
+	case r.Err != nil:
+		s.Errors++
+	case isOKStatus(r.StatusCode):
+		s.OK++
+	default:
+		s.NotOK++
+	}
+
+	if r.Redirected() {
+		s.Redirected++
+	}
+}
+
+func newJSONLink(l link) jsonLink {
+	return jsonLink{URL: l.URL, Locations: newJSONLocations(l.Locations)}
+}
+
+func newJSONResult(r result) jsonResult {
+	jr := jsonResult{
+		URL:          r.URL,
+		RequestURL:   r.RequestURL,
+		Method:       r.Method,
+		StatusCode:   r.StatusCode,
+		Status:       r.Status,
+		DurationMS:   float64(r.Duration) / float64(time.Millisecond),
+		FinalURL:     r.FinalURL,
+		MixedScripts: mixedScripts(r.URL),
+		Locations:    newJSONLocations(r.Locations),
+	}
+
+	if r.Err != nil {
+		jr.Error = &jsonError{Category: r.Category, Detail: r.Detail}
+	}
+
+	for _, hop := range r.Redirects {
+		jr.Redirects = append(jr.Redirects, jsonRedirect{StatusCode: hop.StatusCode, Status: hop.Status, Location: hop.Location})
+	}
+
+	return jr
+}
+
+func newJSONLocations(locations []location) []jsonLocation {
+	jl := make([]jsonLocation, len(locations))
+	for i, loc := range locations {
+		jl[i] = jsonLocation{File: loc.Filepath, Line: loc.Line, Column: loc.Column, Text: loc.Text}
+	}
+
+	return jl
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintJSON(t *testing.T) {
+	src := make(chan result, 3)
+	src <- result{
+		link:       link{URL: "xkcd.com", Locations: []location{{Filepath: "b.md", Line: 3, Column: 1, Text: "xkcd.com"}}},
+		RequestURL: "http://xkcd.com",
+		Method:     "HEAD",
+		Status:     "200 OK",
+		StatusCode: 200,
+		Redirects:  []redirect{{StatusCode: 301, Status: "301 Moved Permanently", Location: "https://xkcd.com/"}},
+		FinalURL:   "https://xkcd.com/",
+		Duration:   1500 * time.Microsecond,
+	}
+	src <- result{
+		link:       link{URL: "http://example.invalid", Locations: []location{{Filepath: "a.md", Line: 1, Column: 5, Text: "http://example.invalid"}}},
+		RequestURL: "http://example.invalid",
+		Method:     "HEAD",
+		Err:        errors.New("lookup failed"),
+		Category:   categoryDNS,
+		Detail:     "no such host example.invalid",
+	}
+	src <- result{
+		link:       link{URL: "http://example.com/hidden", Locations: []location{{Filepath: "a.md", Line: 2, Column: 1}}},
+		StatusCode: 404,
+	}
+	close(src)
+
+	out := bytes.Buffer{}
+	printJSON(&out, src, func(r result) bool { return r.StatusCode != 404 })
+
+	report := jsonReport{}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Expected valid JSON, but got %v:\n%v", err, out.String())
+	}
+
+	expectedSummary := jsonSummary{Total: 3, OK: 1, NotOK: 1, Errors: 1, Redirected: 1}
+	if report.Summary != expectedSummary {
+		t.Errorf("Expected summary %+v, but got %+v", expectedSummary, report.Summary)
+	}
+
+	if len(report.Results) != 2 {
+		t.Fatalf("Expected 2 printable results, but got %+v", report.Results)
+	}
+
+	dns := report.Results[0]
+	if dns.URL != "http://example.invalid" || dns.Error == nil || dns.Error.Category != "dns" || dns.StatusCode != 0 {
+		t.Errorf("Expected the dns error first, but got %+v", dns)
+	}
+
+	ok := report.Results[1]
+	if ok.RequestURL != "http://xkcd.com" || ok.StatusCode != 200 || ok.Error != nil || ok.DurationMS != 1.5 ||
+		ok.FinalURL != "https://xkcd.com/" || len(ok.Redirects) != 1 || ok.Redirects[0].StatusCode != 301 ||
+		len(ok.Locations) != 1 || ok.Locations[0] != (jsonLocation{File: "b.md", Line: 3, Column: 1, Text: "xkcd.com"}) {
+		t.Errorf("Expected every field of the xkcd.com result, but got %+v", ok)
+	}
+}
+
+func TestPrintNDJSON(t *testing.T) {
+	src := make(chan result, 2)
+	src <- result{link: link{URL: "http://example.com/1"}, StatusCode: 200}
+	src <- result{link: link{URL: "http://example.com/2"}, StatusCode: 500}
+	close(src)
+
+	out := bytes.Buffer{}
+	printNDJSON(&out, src, func(result) bool { return true })
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per result, but got %v", out.String())
+	}
+
+	for i, line := range lines {
+		jr := jsonResult{}
+		if err := json.Unmarshal([]byte(line), &jr); err != nil || jr.Locations == nil {
+			t.Errorf("Expected line %v to be a result, but got %v (%v)", i+1, line, err)
+		}
+	}
+}
+
+func TestPrintLinksJSON(t *testing.T) {
+	links := []link{{URL: "xkcd.com", Locations: []location{{Filepath: "a.md", Line: 1, Column: 1, Text: "xkcd.com"}}}}
+
+	out := bytes.Buffer{}
+	printLinksJSON(&out, links)
+
+	doc := struct{ Links []jsonLink }{}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil || len(doc.Links) != 1 || doc.Links[0].URL != "xkcd.com" || doc.Links[0].Locations[0].File != "a.md" {
+		t.Errorf("Expected a document listing xkcd.com, but got %v (%v)", out.String(), err)
+	}
+
+	out.Reset()
+	printLinksNDJSON(&out, links)
+
+	jl := jsonLink{}
+	if err := json.Unmarshal(out.Bytes(), &jl); err != nil || jl.URL != "xkcd.com" {
+		t.Errorf("Expected a line for xkcd.com, but got %v (%v)", out.String(), err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// printJUnit waits for every check to complete, then writes a JUnit XML
+// report with a testsuite per file and a testcase per occurrence of each
+// printable result. Non-OK statuses and errors are failures.
+func printJUnit(w io.Writer, src <-chan result, printable func(result) bool) error {
+	var results []result
+	for r := range src {
+		if printable(r) {
+			results = append(results, r)
+		}
+	}
+
+	byFile := groupByFile(results)
+
+	filepaths := make([]string, 0, len(byFile))
+	for filepath := range byFile {
+		filepaths = append(filepaths, filepath)
+	}
+	sort.Strings(filepaths)
+
+	report := junitTestSuites{Name: "urlstat"}
+	var total time.Duration
+
+	for _, filepath := range filepaths {
+		suite := junitTestSuite{Name: filepath}
+		var suiteTime time.Duration
+
+		for _, o := range byFile[filepath] {
+			testCase := junitTestCase{
+				Name:      fmt.Sprintf("%v:%v %v", o.Line, o.Column, o.URL),
+				ClassName: filepath,
+				Time:      junitSeconds(o.Duration),
+				Failure:   newJUnitFailure(o.result),
+			}
+
+			suite.Tests++
+			if testCase.Failure != nil {
+				suite.Failures++
+			}
+			suiteTime += o.Duration
+
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+
+		suite.Time = junitSeconds(suiteTime)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		total += suiteTime
+
+		report.Suites = append(report.Suites, suite)
+	}
+
+	report.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// newJUnitFailure describes a non-OK status or error, or returns nil for OK results
+func newJUnitFailure(r result) *junitFailure {
+	if r.Err != nil {
+		return &junitFailure{
+			Message: fmt.Sprintf("%v error: %v", r.Category, r.Detail),
+			Type:    r.Category,
+			Body:    fmt.Sprintf("%v error requesting %v: %v", r.Category, r.RequestURL, r.Detail),
+		}
+	}
+
+	if isOKStatus(r.StatusCode) {
+		return nil
+	}
+
+	body := []string{fmt.Sprintf("%v requesting %v", r.Status, r.RequestURL)}
+	for _, hop := range r.Redirects {
+		body = append(body, fmt.Sprintf("  %v -> %v", hop.Status, hop.Location))
+	}
+
+	return &junitFailure{
+		Message: r.Status,
+		Type:    strconv.Itoa(r.StatusCode),
+		Body:    strings.Join(body, "\n"),
+	}
+}
+
+func junitSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
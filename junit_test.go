@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintJUnit(t *testing.T) {
+	src := make(chan result, 3)
+	src <- result{
+		link:       link{URL: "xkcd.com", Locations: []location{{Filepath: "b.md", Line: 3, Column: 1}, {Filepath: "a.md", Line: 9, Column: 2}}},
+		Status:     "200 OK",
+		StatusCode: 200,
+		Duration:   10 * time.Millisecond,
+	}
+	src <- result{
+		link:       link{URL: "http://example.com/gone", Locations: []location{{Filepath: "a.md", Line: 1, Column: 1}}},
+		RequestURL: "http://example.com/gone",
+		Status:     "404 Not Found",
+		StatusCode: 404,
+		Duration:   5 * time.Millisecond,
+	}
+	src <- result{
+		link:       link{URL: "http://example.invalid", Locations: []location{{Filepath: "b.md", Line: 1, Column: 1}}},
+		RequestURL: "http://example.invalid",
+		Err:        errors.New("lookup failed"),
+		Category:   categoryDNS,
+		Detail:     "no such host example.invalid",
+	}
+	close(src)
+
+	out := bytes.Buffer{}
+	if err := printJUnit(&out, src, func(result) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(out.String(), xml.Header) {
+		t.Errorf("Expected an XML header, but got %v", out.String())
+	}
+
+	report := junitTestSuites{}
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Expected valid XML, but got %v:\n%v", err, out.String())
+	}
+
+	if report.Tests != 4 || report.Failures != 2 || len(report.Suites) != 2 {
+		t.Fatalf("Expected 4 tests with 2 failures in 2 suites, but got %+v", report)
+	}
+
+	a := report.Suites[0]
+	if a.Name != "a.md" || a.Tests != 2 || a.Failures != 1 || a.Time != "0.015" {
+		t.Errorf("Expected a.md to have 2 tests and 1 failure, but got %+v", a)
+	}
+
+	if tc := a.TestCases[0]; tc.Name != "1:1 http://example.com/gone" || tc.ClassName != "a.md" || tc.Failure == nil || tc.Failure.Type != "404" || tc.Failure.Message != "404 Not Found" {
+		t.Errorf("Expected the 404 to be a failure, but got %+v", tc)
+	}
+
+	if tc := a.TestCases[1]; tc.Name != "9:2 xkcd.com" || tc.Failure != nil {
+		t.Errorf("Expected xkcd.com to pass, but got %+v", tc)
+	}
+
+	b := report.Suites[1]
+	if tc := b.TestCases[0]; tc.Failure == nil || tc.Failure.Type != "dns" || !strings.Contains(tc.Failure.Message, "no such host example.invalid") {
+		t.Errorf("Expected the dns error to be a failure, but got %+v", tc)
+	}
+}
@@ -2,53 +2,215 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/fatih/color"
+	"github.com/jmks/urlstat/hosts"
 	"github.com/jmks/urlstat/options"
 	"github.com/jmks/urlstat/tld"
 )
 
+// Exit codes
+const (
+	exitOK       = 0 // every URL is OK
+	exitBroken   = 1 // a URL failed in a class given to -fail-on
+	exitUsage    = 2 // invalid arguments or flags
+	exitInternal = 3 // a file couldn't be read, or the output couldn't be written
+)
+
 func main() {
-	opts := options.Parse()
+	if isTLDCommand(os.Args[1:]) {
+		os.Exit(runTLD(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	os.Exit(run(options.Parse(), os.Stdout, os.Stderr))
+}
+
+// isTLDCommand returns whether args run 'urlstat tld update'. A directory
+// named tld is scanned as ./tld instead.
+func isTLDCommand(args []string) bool {
+	return len(args) > 1 && args[0] == "tld" && args[1] == "update"
+}
 
+// run checks the URLs in the files opts gives, writing the results to stdout
+// and errors to stderr, and returns the exit code
+func run(opts options.Options, stdout, stderr io.Writer) int {
 	if !opts.IsValid() {
-		opts.PrintError()
-		os.Exit(1)
+		opts.PrintError(stderr)
+		return exitUsage
 	}
 
-	filepathSrc := filepathProducer(opts.Filepaths)
-	urlSrc := urlProducer(filepathSrc)
-	uniqURLs := uniqAccumulator(urlSrc)
+	errLog := &errorLog{w: stderr}
+
+	if err := loadTLDs(opts.TLDFile(), stderr); err != nil {
+		fmt.Fprintf(stderr, "Invalid -tld-file: %v\n", err)
+		return exitUsage
+	}
+	// -tld-mode publicsuffix, -group-by domain and -limit-by domain can't work
+	// without the list, so they aren't quietly dropped
+	if opts.UsesPublicSuffixes() {
+		if err := loadPublicSuffixList(opts.PublicSuffixFile()); err != nil {
+			fmt.Fprintf(stderr, "Error '%v'\n", err)
+			return exitUsage
+		}
+	}
+	tld.SetMode(opts.TLDMode())
+	tld.Allow(opts.AllowedTLDs(), opts.AllowedHosts())
+	tld.AllowSpecialUse(opts.SpecialUse())
+	hosts.ExcludeIPRanges(opts.ExcludedIPRanges())
+
+	extractCfg := extractConfig{
+		markdownCode: opts.MarkdownCode(),
+		binary:       opts.ScanBinary(),
+		stdin:        opts.Stdin(),
+		base:         opts.BaseURL(),
+		root:         opts.Root(),
+	}
+
+	filepathSrc := filepathProducer(opts.Filepaths, walkConfig{include: opts.Include(), exclude: opts.Exclude()}, errLog)
+	matchSrc := urlProducer(filepathSrc, extractCfg, errLog)
+	uniqLinks := uniqAccumulator(matchSrc)
+
+	checkCfg := checkConfig{
+		concurrency: opts.Concurrency(),
+		perHost:     opts.PerHost(),
+		limitBy:     opts.LimitBy(),
+		method:      opts.Method(),
+		timeout:     opts.Timeout(),
+		retries:     opts.Retries(),
+		backoff:     opts.Backoff(),
+	}
+
+	ctx := context.Background()
+	if opts.Deadline() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Deadline())
+		defer cancel()
+	}
 
 	if opts.ListOnly() {
-		for _, url := range uniqURLs {
-			fmt.Println(url)
+		errLog.add(printLinks(stdout, uniqLinks, opts))
+		return errLog.exitCode(exitOK)
+	}
+
+	failures := failureTracker{}
+	results := failures.track(statusProducer(ctx, uniqLinks, checkCfg), func(r result) bool {
+		return opts.FailsOn(r.Class())
+	})
+	printable := func(r result) bool {
+		return isResultPrintable(r, opts)
+	}
+
+	switch opts.Format() {
+	case "json":
+		errLog.add(printJSON(stdout, results, printable))
+	case "ndjson":
+		errLog.add(printNDJSON(stdout, results, printable))
+	case "junit":
+		errLog.add(printJUnit(stdout, results, printable))
+	case "sarif":
+		errLog.add(printSARIF(stdout, results, printable))
+	default:
+		if opts.Stream() {
+			errLog.add(printStatuses(stdout, results, opts))
+		} else {
+			errLog.add(printReport(stdout, results, opts))
 		}
-	} else {
-		printStatuses(uniqURLs, opts)
 	}
+
+	if failures.count() > 0 {
+		return errLog.exitCode(exitBroken)
+	}
+
+	return errLog.exitCode(exitOK)
 }
 
-func filepathProducer(filepaths []string) <-chan string {
+func printLinks(w io.Writer, links []link, opts options.Options) error {
+	switch opts.Format() {
+	case "json":
+		return printLinksJSON(w, links)
+	case "ndjson":
+		return printLinksNDJSON(w, links)
+	default:
+		for _, link := range links {
+			if _, err := fmt.Fprintln(w, link.URL); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// location of a URL within a scanned file
+type location struct {
+	Filepath string
+	Line     int
+	Column   int
+	Text     string
+}
+
+func (loc location) String() string {
+	return fmt.Sprintf("%v:%v:%v", loc.Filepath, loc.Line, loc.Column)
+}
+
+// match is a URL extracted from a file and where it was found
+type match struct {
+	URL string
+	location
+}
+
+// link is a unique URL and every location it was found at
+type link struct {
+	URL       string
+	Locations []location
+}
+
+// filepathProducer sends each file path, and every included file found in
+// each directory path
+func filepathProducer(filepaths []string, cfg walkConfig, errLog *errorLog) <-chan string {
 	dest := make(chan string, 100)
 
 	go func() {
+		stdinRead := false
+
 		for _, filepath := range filepaths {
 			if len(filepath) == 0 {
 				continue
 			}
 
-			if _, err := os.Stat(filepath); !os.IsNotExist(err) {
-				dest <- filepath
+			// stdin can only be read once
+			if filepath == options.StdinPath {
+				if !stdinRead {
+					dest <- filepath
+				}
+				stdinRead = true
+				continue
+			}
+
+			info, err := os.Stat(filepath)
+			if err != nil {
+				errLog.add(err)
+				continue
 			}
+
+			if info.IsDir() {
+				walkDir(filepath, cfg, func(path string) { dest <- path }, errLog)
+				continue
+			}
+
+			dest <- filepath
 		}
 		close(dest)
 	}()
@@ -56,8 +218,8 @@ func filepathProducer(filepaths []string) <-chan string {
 	return dest
 }
 
-func urlProducer(filepathSrc <-chan string) <-chan string {
-	dest := make(chan string, 100)
+func urlProducer(filepathSrc <-chan string, cfg extractConfig, errLog *errorLog) <-chan match {
+	dest := make(chan match, 100)
 
 	go func() {
 		wg := sync.WaitGroup{}
@@ -68,14 +230,27 @@ func urlProducer(filepathSrc <-chan string) <-chan string {
 			go func(path string) {
 				defer wg.Done()
 
-				file, err := os.Open(path)
+				file, err := cfg.open(path)
 				if err != nil {
-					fmt.Printf("Error '%v'\n", err)
+					errLog.add(err)
 					return
 				}
+				defer file.Close()
 
-				for _, url := range extractURLs(file) {
-					dest <- url
+				source := bufio.NewReader(file)
+				if !cfg.binary && looksBinary(source) {
+					return
+				}
+
+				extract := extractorFor(path, cfg)
+				matches, err := extract(source)
+				if err != nil {
+					errLog.add(fmt.Errorf("reading %v: %v", path, err))
+				}
+
+				for _, m := range matches {
+					m.Filepath = path
+					dest <- m
 				}
 			}(filepath)
 		}
@@ -87,56 +262,380 @@ func urlProducer(filepathSrc <-chan string) <-chan string {
 	return dest
 }
 
-func uniqAccumulator(src <-chan string) []string {
-	uniq := make(map[string]bool)
+// uniqAccumulator collapses matches into links, ordered by where each URL first appears
+func uniqAccumulator(src <-chan match) []link {
+	uniq := make(map[string]*link)
 
-	for str := range src {
-		uniq[str] = true
+	for m := range src {
+		l, ok := uniq[m.URL]
+		if !ok {
+			l = &link{URL: m.URL}
+			uniq[m.URL] = l
+		}
+
+		l.Locations = append(l.Locations, m.location)
 	}
 
-	urls := make([]string, len(uniq))
-	i := 0
-	for url := range uniq {
-		urls[i] = url
-		i++
+	links := make([]link, 0, len(uniq))
+	for _, l := range uniq {
+		sort.Slice(l.Locations, func(i, j int) bool {
+			return locationLess(l.Locations[i], l.Locations[j])
+		})
+		links = append(links, *l)
 	}
 
-	return urls
+	sort.Slice(links, func(i, j int) bool {
+		return locationLess(links[i].Locations[0], links[j].Locations[0])
+	})
+
+	return links
 }
 
-func extractURLs(source io.Reader) []string {
-	var urls []string
+func locationLess(a, b location) bool {
+	if a.Filepath != b.Filepath {
+		return a.Filepath < b.Filepath
+	}
 
-	scanner := bufio.NewScanner(source)
-	for scanner.Scan() {
-		for _, word := range strings.Fields(scanner.Text()) {
-			u, err := url.Parse(word)
-			if err != nil {
-				continue
-			}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
 
-			// skip all non-http[s]? schemes
-			if len(u.Scheme) > 0 && !strings.HasPrefix(u.Scheme, "http") {
-				continue
+	return a.Column < b.Column
+}
+
+// extractor finds URLs, and where they are, in the contents of a file,
+// returning those found before any error reading it
+type extractor func(io.Reader) ([]match, error)
+
+// extractConfig holds the options that change how URLs are extracted
+type extractConfig struct {
+	markdownCode bool
+	binary       bool
+	stdin        io.Reader
+	base         *url.URL
+	root         string
+}
+
+// documentURL returns the URL the file at path is published at, from its path
+// relative to root under the base URL, or nil without a base URL or when the
+// file isn't under root. Stdin is published at the base URL.
+func (cfg extractConfig) documentURL(path string) *url.URL {
+	if cfg.base == nil {
+		return nil
+	}
+
+	if path == options.StdinPath {
+		return cfg.base
+	}
+
+	root, err := filepath.Abs(cfg.root)
+	if err != nil {
+		return nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+
+	return cfg.base.ResolveReference(&url.URL{Path: filepath.ToSlash(rel)})
+}
+
+// open returns the contents of the file at path, or stdin for options.StdinPath
+func (cfg extractConfig) open(path string) (io.ReadCloser, error) {
+	if path == options.StdinPath {
+		return io.NopCloser(cfg.stdin), nil
+	}
+
+	return os.Open(path)
+}
+
+// sniffLen is how much of a file is looked at to decide if it's binary
+const sniffLen = 512
+
+// looksBinary sniffs the start of source for NUL bytes or a binary content
+// type, without consuming it
+func looksBinary(source *bufio.Reader) bool {
+	head, _ := source.Peek(sniffLen)
+	if bytes.IndexByte(head, 0) != -1 {
+		return true
+	}
+
+	contentType := http.DetectContentType(head)
+	for _, text := range []string{"text/", "xml", "json", "javascript"} {
+		if strings.Contains(contentType, text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// extractorFor chooses an extractor based on the file extension, defaulting to plain text
+func extractorFor(path string, cfg extractConfig) extractor {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return func(source io.Reader) ([]match, error) {
+			return extractHTMLURLs(source, cfg.documentURL(path))
+		}
+	case ".md", ".markdown":
+		return func(source io.Reader) ([]match, error) {
+			return extractMarkdownURLs(source, cfg.markdownCode, cfg.documentURL(path))
+		}
+	default:
+		return extractURLs
+	}
+}
+
+func extractURLs(source io.Reader) ([]match, error) {
+	var matches []match
+
+	words := newWordScanner(source)
+	for words.Scan() {
+		text, lead := trimProse(words.Word())
+
+		u, ok := parseURL(text)
+		if !ok {
+			continue
+		}
+
+		line, column := words.Position()
+		matches = append(matches, match{
+			URL:      urlString(u),
+			location: location{Line: line, Column: column + lead, Text: text},
+		})
+	}
+
+	return matches, words.Err()
+}
+
+// wordScanner splits a stream into whitespace separated words, keeping the
+// line and column each starts at. Unlike bufio.Scanner, there's no limit on
+// the length of a line.
+type wordScanner struct {
+	r    *bufio.Reader
+	word strings.Builder
+	err  error
+
+	line, column         int
+	wordLine, wordColumn int
+}
+
+func newWordScanner(source io.Reader) *wordScanner {
+	return &wordScanner{r: bufio.NewReader(source), line: 1, column: 1}
+}
+
+// Scan advances to the next word, returning false at the end of the stream
+// or on an error
+func (s *wordScanner) Scan() bool {
+	s.word.Reset()
+
+	for {
+		r, size, err := s.r.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
 			}
 
-			if len(u.Host) > 0 && !strings.HasPrefix(u.Host, "localhost") && !tld.HasKnownTLD(u.Host) {
-				continue
+			return s.word.Len() > 0
+		}
+
+		if unicode.IsSpace(r) {
+			if r == '\n' {
+				s.line++
+				s.column = 1
+			} else {
+				s.column += size
 			}
 
-			if len(u.Host) == 0 && len(u.Path) > 0 && !(looksLikeURN(u.Path) && tld.HasKnownTLD(u.Path)) {
-				continue
+			if s.word.Len() > 0 {
+				return true
 			}
 
-			if len(u.Host) == 0 && len(u.Path) == 0 {
-				continue
+			continue
+		}
+
+		if s.word.Len() == 0 {
+			s.wordLine, s.wordColumn = s.line, s.column
+		}
+
+		s.word.WriteRune(r)
+		s.column += size
+	}
+}
+
+// Word returns the word found by the last call to Scan
+func (s *wordScanner) Word() string {
+	return s.word.String()
+}
+
+// Position returns the 1-based line and byte column the word starts at
+func (s *wordScanner) Position() (int, int) {
+	return s.wordLine, s.wordColumn
+}
+
+// Err returns the first error reading the stream, other than io.EOF
+func (s *wordScanner) Err() error {
+	return s.err
+}
+
+// parseURL parses s, returning false unless it looks like a checkable http[s] URL or URN
+func parseURL(s string) (*url.URL, bool) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, false
+	}
+
+	// skip all non-http[s]? schemes
+	if len(u.Scheme) > 0 && !strings.HasPrefix(u.Scheme, "http") {
+		return nil, false
+	}
+
+	if len(u.Host) > 0 && !hosts.Known(u.Host) {
+		return nil, false
+	}
+
+	if len(u.Host) == 0 && len(u.Path) > 0 && !isURN(u.Path) {
+		return nil, false
+	}
+
+	if len(u.Host) == 0 && len(u.Path) == 0 {
+		return nil, false
+	}
+
+	return u, true
+}
+
+// resolveURL is parseAbsoluteURL for links in a document published at base,
+// resolving relative and root-relative links against it. Without a base, or
+// for links within the document itself, like #top, only absolute URLs are
+// found. Resolved URLs must have a known host, like absolute ones.
+func resolveURL(s string, base *url.URL) (string, bool) {
+	if u, ok := parseAbsoluteURL(s); ok || base == nil {
+		return u, ok
+	}
+
+	ref, err := url.Parse(s)
+	if err != nil || len(ref.Scheme) > 0 || len(ref.Host) > 0 || len(ref.Path) == 0 {
+		return "", false
+	}
+
+	u := base.ResolveReference(ref)
+	if !hosts.Known(u.Host) {
+		return "", false
+	}
+
+	return urlString(u), true
+}
+
+// urlString is u.String(), but keeps an internationalized host readable
+// rather than percent-encoding it
+func urlString(u *url.URL) string {
+	if len(u.Host) == 0 {
+		return u.String()
+	}
+
+	escapedHost := (&url.URL{Host: u.Host}).String()
+	return strings.Replace(u.String(), escapedHost, "//"+u.Host, 1)
+}
+
+// parseAbsoluteURL is parseURL for markup, where links without a scheme are
+// relative to the document rather than URNs
+func parseAbsoluteURL(s string) (string, bool) {
+	if strings.HasPrefix(s, "//") {
+		s = "https:" + s
+	}
+
+	u, ok := parseURL(s)
+	if !ok || len(u.Scheme) == 0 {
+		return "", false
+	}
+
+	return urlString(u), true
+}
+
+// field is a whitespace separated word and its 1-based column within a line
+type field struct {
+	text   string
+	column int
+}
+
+// fields splits s like strings.Fields, but keeps the column of each word
+func fields(s string) []field {
+	var found []field
+
+	start := -1
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				found = append(found, field{text: s[start:i], column: start + 1})
+				start = -1
 			}
+		} else if start < 0 {
+			start = i
+		}
+	}
 
-			urls = append(urls, u.String())
+	if start >= 0 {
+		found = append(found, field{text: s[start:], column: start + 1})
+	}
+
+	return found
+}
+
+// proseFields splits s into words like fields, trimming each word of the
+// surrounding punctuation it picks up in prose
+func proseFields(s string) []field {
+	found := fields(s)
+	for i, f := range found {
+		text, lead := trimProse(f.text)
+		found[i] = field{text: text, column: f.column + lead}
+	}
+
+	return found
+}
+
+const (
+	leadingPunctuation  = "\"'`([{<*_"
+	trailingPunctuation = "\"'`.,:;!?*_~>"
+)
+
+var closingBrackets = map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+// trimProse strips the sentence punctuation, quotes and brackets surrounding a
+// URL in prose, returning the URL and how many bytes were trimmed from its start.
+// Closing brackets are only trimmed when unbalanced, so paths like
+// /wiki/Go_(disambiguation) are kept whole.
+func trimProse(word string) (string, int) {
+	start, end := 0, len(word)
+
+	for start < end && strings.IndexByte(leadingPunctuation, word[start]) >= 0 {
+		start++
+	}
+
+	for end > start {
+		c := word[end-1]
+
+		if strings.IndexByte(trailingPunctuation, c) >= 0 {
+			end--
+			continue
+		}
+
+		open, isClosing := closingBrackets[c]
+		if isClosing && strings.Count(word[start:end], string(open)) < strings.Count(word[start:end], string(c)) {
+			end--
+			continue
 		}
+
+		break
 	}
 
-	return urls
+	return word[start:end], start
 }
 
 var urnPattern = regexp.MustCompile(`^(?P<host>(?:\w+\.)+)(?P<tld>\w+).*`)
@@ -145,47 +644,123 @@ func looksLikeURN(s string) bool {
 	return urnPattern.MatchString(s)
 }
 
-func printStatuses(urls []string, opts options.Options) {
-	wg := sync.WaitGroup{}
+// isURN returns whether path, from a URL without a scheme, starts with a
+// known host. A single label host, like jenkins/job/build, or an IP address
+// must be followed by a path so that plain words and version numbers aren't
+// mistaken for hosts.
+func isURN(path string) bool {
+	host, _, hasPath := strings.Cut(path, "/")
+	if _, isIP := hosts.ParseIP(host); isIP && !hasPath {
+		return false
+	}
 
-	for _, url := range urls {
-		wg.Add(1)
+	if !looksLikeURN(path) && !hasPath {
+		return false
+	}
 
-		go func(url string) {
-			// treat url with prefix 'http' as URN
-			reqURL := url
-			if !strings.HasPrefix(url, "http") {
-				reqURL = fmt.Sprintf("http://%v", url)
-			}
+	return hosts.Known(host)
+}
 
-			if resp, err := http.Head(reqURL); err != nil {
-				redden := color.New(color.FgRed).SprintFunc()
-				fmt.Printf("%v : %v\n", redden("HTTP ERROR"), url)
-			} else {
-				defer resp.Body.Close()
+// printStatuses writes each printable result as soon as its check completes,
+// stopping at the first error writing to w
+func printStatuses(w io.Writer, src <-chan result, opts options.Options) error {
+	out := &errWriter{w: w}
 
-				if isStatusPrintable(resp.StatusCode, opts) {
-					colorize := statusCodePrinterFunc(resp.StatusCode)
-					fmt.Printf("%v : %v\n", colorize(resp.Status), url)
-				}
-			}
+	for r := range src {
+		if !isResultPrintable(r, opts) {
+			continue
+		}
+
+		fmt.Fprintf(out, "%v : %v\n", statusText(r, opts), urlText(r))
+		if opts.RedirectsOnly() {
+			printRedirects(out, r.Redirects, "    ")
+		}
+		for _, loc := range r.Locations {
+			fmt.Fprintf(out, "    %v\n", loc)
+		}
+
+		if out.err != nil {
+			return out.err
+		}
+	}
+
+	return nil
+}
+
+func printRedirects(w io.Writer, redirects []redirect, indent string) {
+	for _, hop := range redirects {
+		fmt.Fprintf(w, "%v%v : %v\n", indent, statusCodePrinterFunc(hop.StatusCode)(hop.Status), hop.Location)
+	}
+}
+
+func isResultPrintable(r result, opts options.Options) bool {
+	if opts.RedirectsOnly() && !r.Redirected() {
+		return false
+	}
+
+	if r.Err != nil {
+		return opts.IsErrorListable(r.Category)
+	}
+
+	return !opts.ErrorsOnly() && isStatusPrintable(r.StatusCode, opts)
+}
+
+func statusText(r result, opts options.Options) string {
+	text := resultStatusText(r, opts)
+
+	if opts.WarnMixedScript() {
+		if scripts := mixedScripts(r.URL); len(scripts) > 0 {
+			yellow := color.New(color.FgYellow).SprintFunc()
+			text += yellow(fmt.Sprintf(" (mixed scripts: %v)", strings.Join(scripts, ", ")))
+		}
+	}
+
+	return text
+}
+
+func resultStatusText(r result, opts options.Options) string {
+	if r.Err != nil {
+		redden := color.New(color.FgRed).SprintFunc()
+		return redden(fmt.Sprintf("%v ERROR (%v)", strings.ToUpper(r.Category), r.Detail))
+	}
+
+	status := r.Status
+	if r.Method == http.MethodGet {
+		status = fmt.Sprintf("%v (GET)", status)
+	}
 
-			wg.Done()
-		}(url)
+	if opts.WarnPermanent() && r.PermanentlyRedirected() {
+		yellow := color.New(color.FgYellow).SprintFunc()
+		return yellow(fmt.Sprintf("%v (moved permanently)", status))
 	}
 
-	wg.Wait()
+	colorize := statusCodePrinterFunc(r.StatusCode)
+	return colorize(status)
+}
+
+// urlText is the URL of a result, followed by where it finally redirected to
+func urlText(r result) string {
+	if r.Redirected() {
+		return fmt.Sprintf("%v -> %v", r.URL, r.FinalURL)
+	}
+
+	return r.URL
 }
 
 func isStatusPrintable(status int, opts options.Options) bool {
-	return (status == 200 && opts.IsOkListable()) || (status != 200 && opts.IsNotOkListable())
+	return (isOKStatus(status) && opts.IsOkListable()) || (!isOKStatus(status) && opts.IsNotOkListable())
+}
+
+// isOKStatus returns whether status is 200 OK, the only status not reported as broken
+func isOKStatus(status int) bool {
+	return status == http.StatusOK
 }
 
 func statusCodePrinterFunc(code int) func(...interface{}) string {
-	switch code {
-	case 200:
+	switch {
+	case code == 200:
 		return color.New(color.FgGreen).SprintFunc()
-	case 404:
+	case code == 404 || isRedirect(code):
 		return color.New(color.FgYellow).SprintFunc()
 	default:
 		return color.New(color.FgRed).SprintFunc()
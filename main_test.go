@@ -1,8 +1,13 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"io"
 	"strings"
 	"testing"
+
+	"github.com/jmks/urlstat/tld"
 )
 
 func TestExtractURLs(t *testing.T) {
@@ -16,7 +21,7 @@ func TestExtractURLs(t *testing.T) {
 		"URNs like xkcd.com or mail.google.com":                      []string{"xkcd.com", "mail.google.com"},
 		"local addresses like http://localhost:9000":                 []string{"http://localhost:9000"},
 		"local addresses must have a scheme localhost:9000":          []string{},
-		// TODO: want to extract these
+		// HTML is only parsed in .html files, see TestExtractHTMLURLs
 		`embedded URLs like <a href="http://xkcd.com/974/"></a>`: []string{},
 		// Don't extract URI-looking things without a known TLD
 		"Class.new.method": []string{},
@@ -24,7 +29,33 @@ func TestExtractURLs(t *testing.T) {
 	}
 
 	for source, expected := range examples {
-		actual := extractURLs(strings.NewReader(source))
+		actual := matchedURLs(extractURLs(strings.NewReader(source)))
+
+		if !stringSlicesEqual(actual, expected) {
+			t.Errorf("Expected %v from '%v', but actually got %v", expected, source, actual)
+		}
+	}
+}
+
+func TestExtractURLsFromProse(t *testing.T) {
+	examples := map[string][]string{
+		"(see http://example.com/foo).":                         []string{"http://example.com/foo"},
+		`"http://example.com",`:                                 []string{"http://example.com"},
+		"<http://example.com>":                                  []string{"http://example.com"},
+		"'http://example.com/quoted'?":                          []string{"http://example.com/quoted"},
+		"see http://en.wikipedia.org/wiki/Go_(disambiguation).": []string{"http://en.wikipedia.org/wiki/Go_(disambiguation)"},
+		"(http://en.wikipedia.org/wiki/Go_(disambiguation))":    []string{"http://en.wikipedia.org/wiki/Go_(disambiguation)"},
+		"is it http://example.com/faq?":                         []string{"http://example.com/faq"},
+		"try http://example.com/search?q=go!":                   []string{"http://example.com/search?q=go"},
+		"[http://example.com/bracketed]":                        []string{"http://example.com/bracketed"},
+		"http://example.com/a_(b)_c, and more":                  []string{"http://example.com/a_(b)_c"},
+		"emphasis *http://example.com/strong*":                  []string{"http://example.com/strong"},
+		"a URN (xkcd.com); in parentheses":                      []string{"xkcd.com"},
+		"only punctuation ()., is ignored":                      []string{},
+	}
+
+	for source, expected := range examples {
+		actual := matchedURLs(extractURLs(strings.NewReader(source)))
 
 		if !stringSlicesEqual(actual, expected) {
 			t.Errorf("Expected %v from '%v', but actually got %v", expected, source, actual)
@@ -32,6 +63,97 @@ func TestExtractURLs(t *testing.T) {
 	}
 }
 
+func TestTrimProse(t *testing.T) {
+	examples := []struct {
+		word     string
+		expected string
+		lead     int
+	}{
+		{"http://example.com", "http://example.com", 0},
+		{"(http://example.com).", "http://example.com", 1},
+		{`"<http://example.com>",`, "http://example.com", 2},
+		{"http://example.com/(a)", "http://example.com/(a)", 0},
+		{"http://example.com/(a))", "http://example.com/(a)", 0},
+		{"http://example.com/a)", "http://example.com/a", 0},
+		{"{http://example.com/}", "http://example.com/", 1},
+		{"...", "", 0},
+	}
+
+	for _, example := range examples {
+		actual, lead := trimProse(example.word)
+
+		if actual != example.expected || lead != example.lead {
+			t.Errorf("Expected '%v' to trim to '%v' (%v leading), but got '%v' (%v leading)", example.word, example.expected, example.lead, actual, lead)
+		}
+	}
+}
+
+func TestExtractURLsLocations(t *testing.T) {
+	source := "first line\n  see http://example.com and\nthen xkcd.com/974 twice (xkcd.com/974)."
+	expected := []location{
+		{Line: 2, Column: 7, Text: "http://example.com"},
+		{Line: 3, Column: 6, Text: "xkcd.com/974"},
+		{Line: 3, Column: 26, Text: "xkcd.com/974"},
+	}
+
+	matches, err := extractURLs(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v matches, but got %v", len(expected), matches)
+	}
+
+	for i, m := range matches {
+		if m.location != expected[i] {
+			t.Errorf("Expected %v to be found at %+v, but got %+v", m.URL, expected[i], m.location)
+		}
+	}
+}
+
+func TestUniqAccumulator(t *testing.T) {
+	src := make(chan match, 3)
+	src <- match{URL: "xkcd.com", location: location{Filepath: "b.txt", Line: 1, Column: 1}}
+	src <- match{URL: "http://example.com", location: location{Filepath: "a.txt", Line: 4, Column: 2}}
+	src <- match{URL: "xkcd.com", location: location{Filepath: "a.txt", Line: 9, Column: 1}}
+	close(src)
+
+	links := uniqAccumulator(src)
+
+	if actual := linkURLs(links); !stringSlicesEqual(actual, []string{"http://example.com", "xkcd.com"}) {
+		t.Fatalf("Expected links ordered by first location, but got %v", actual)
+	}
+
+	expected := []location{{Filepath: "a.txt", Line: 9, Column: 1}, {Filepath: "b.txt", Line: 1, Column: 1}}
+	if len(links[1].Locations) != 2 || links[1].Locations[0] != expected[0] || links[1].Locations[1] != expected[1] {
+		t.Errorf("Expected locations %v, but got %v", expected, links[1].Locations)
+	}
+}
+
+// matchedURLs returns the URLs of matches, or the error as the only URL
+func matchedURLs(matches []match, err error) []string {
+	if err != nil {
+		return []string{"error: " + err.Error()}
+	}
+
+	var urls []string
+	for _, m := range matches {
+		urls = append(urls, m.URL)
+	}
+
+	return urls
+}
+
+func linkURLs(links []link) []string {
+	var urls []string
+	for _, l := range links {
+		urls = append(urls, l.URL)
+	}
+
+	return urls
+}
+
 func stringSlicesEqual(a, b []string) bool {
 	if a == nil && b == nil {
 		return true
@@ -57,3 +179,144 @@ func stringSlicesEqual(a, b []string) bool {
 
 	return true
 }
+
+func TestLooksBinary(t *testing.T) {
+	examples := map[string]bool{
+		"plain text with http://example.com":  false,
+		"<!DOCTYPE html><html></html>":        false,
+		`{"url": "http://example.com"}`:       false,
+		"":                                    false,
+		"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR": true,
+		"text until a \x00 byte":              true,
+		"%PDF-1.4 binary":                     true,
+	}
+
+	for content, expected := range examples {
+		source := bufio.NewReader(strings.NewReader(content))
+
+		if actual := looksBinary(source); actual != expected {
+			t.Errorf("Expected '%q' to look binary: %v, but got %v", content, expected, actual)
+		}
+
+		if rest, _ := io.ReadAll(source); string(rest) != content {
+			t.Errorf("Expected sniffing not to consume '%q', but %q was left", content, rest)
+		}
+	}
+}
+
+func TestExtractURLsFromLongLines(t *testing.T) {
+	// well past bufio.Scanner's 64 KiB token limit
+	filler := strings.Repeat("x", 4*1024*1024)
+	longWord := "http://example.com/" + filler
+
+	examples := map[string]string{
+		"minified.js":  "var a=1;" + strings.Repeat("a+=1;", 1024*1024) + " http://example.com/first " + filler + " http://example.com/last",
+		"single.json":  `{"data":"` + filler + `", "url": "http://example.com/first"} http://example.com/last`,
+		"index.html":   `<p>` + filler + `</p><a href="http://example.com/first">a</a><img src="http://example.com/last">`,
+		"README.md":    filler + " [first](http://example.com/first) <http://example.com/last>",
+		"links.md":     "[first](http://example.com/first) " + strings.Repeat("[a](http://example.com/a) `code` <http://example.com/b> ", 50000) + "<http://example.com/last>",
+		"long-url.txt": "http://example.com/first " + longWord + " http://example.com/last",
+	}
+
+	for filename, source := range examples {
+		urls := matchedURLs(extractorFor(filename, extractConfig{})(strings.NewReader(source)))
+
+		if !containsString(urls, "http://example.com/first") || !containsString(urls, "http://example.com/last") {
+			t.Errorf("Expected URLs on either side of a %v MiB line in %v, but got %.100v", len(source)/1024/1024, filename, urls)
+		}
+	}
+
+	matches, err := extractURLs(strings.NewReader(filler + " http://example.com/\nxkcd.com"))
+	if err != nil || len(matches) != 2 || matches[0].Column != len(filler)+2 || matches[1].Line != 2 {
+		t.Errorf("Expected locations after a long line to be right, but got %+v (%v)", matches, err)
+	}
+}
+
+func TestExtractURLsReportsReadErrors(t *testing.T) {
+	for _, filename := range []string{"notes.txt", "README.md", "index.html"} {
+		source := io.MultiReader(strings.NewReader("http://example.com/before "), errReader{})
+
+		matches, err := extractorFor(filename, extractConfig{})(source)
+		if err != errRead {
+			t.Errorf("Expected the read error from %v, but got %v", filename, err)
+		}
+
+		if filename != "index.html" && (len(matches) != 1 || matches[0].URL != "http://example.com/before") {
+			t.Errorf("Expected URLs before the error in %v, but got %v", filename, matches)
+		}
+	}
+}
+
+var errRead = errors.New("disk on fire")
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errRead
+}
+
+func containsString(ss []string, s string) bool {
+	for _, candidate := range ss {
+		if candidate == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestStdinContent(t *testing.T) {
+	errLog := &errorLog{w: io.Discard}
+	cfg := extractConfig{stdin: strings.NewReader("diff --git a/README.md b/README.md\n+see https://example.com/docs\n")}
+
+	paths := filepathProducer([]string{"-", "-"}, walkConfig{}, errLog)
+	matches := []match{}
+	for m := range urlProducer(paths, cfg, errLog) {
+		matches = append(matches, m)
+	}
+
+	if len(matches) != 1 || matches[0].URL != "https://example.com/docs" || matches[0].String() != "-:2:6" {
+		t.Errorf("Expected a URL from stdin at -:2:6, but got %+v", matches)
+	}
+
+	if errLog.count != 0 {
+		t.Errorf("Expected reading stdin twice to be skipped, but got %v errors", errLog.count)
+	}
+}
+
+func TestExtractAllowedHosts(t *testing.T) {
+	defer tld.Allow(nil, nil)
+	defer tld.AllowSpecialUse(false)
+
+	source := "http://jenkins/job/1 http://build.corp:8080/ docs.corp/setup jenkins/job/2 jenkins wiki.test/home http://app.test/"
+
+	if urls := matchedURLs(extractURLs(strings.NewReader(source))); len(urls) != 0 {
+		t.Errorf("Expected unknown hosts to be skipped, but got %v", urls)
+	}
+
+	tld.Allow([]string{"corp"}, []string{"jenkins"})
+	tld.AllowSpecialUse(true)
+
+	expected := []string{"http://jenkins/job/1", "http://build.corp:8080/", "docs.corp/setup", "jenkins/job/2", "wiki.test/home", "http://app.test/"}
+	if urls := matchedURLs(extractURLs(strings.NewReader(source))); !stringSlicesEqual(urls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, urls)
+	}
+
+	paths := "files in test/fixtures/a.json and local/bin/tool and see invalid/path"
+	if urls := matchedURLs(extractURLs(strings.NewReader(paths))); len(urls) != 0 {
+		t.Errorf("Expected paths under special-use names not to be URLs, but got %v", urls)
+	}
+}
+
+func TestExtractInternationalizedURLs(t *testing.T) {
+	source := "http://hotel.xn--w4r85el8fhu5dnra/ http://hotel.嘉里大酒店/ xn--bcher-kva.com/books http://xn--a.com/"
+	urls := matchedURLs(extractURLs(strings.NewReader(source)))
+
+	if len(urls) != 3 || urls[1] != "http://hotel.嘉里大酒店/" || urls[2] != "xn--bcher-kva.com/books" {
+		t.Fatalf("Expected both forms of hotel.嘉里大酒店 and xn--bcher-kva.com, but got %v", urls)
+	}
+
+	if requestURL(urls[0]) != requestURL(urls[1]) || requestURL(urls[1]) != "http://hotel.xn--w4r85el8fhu5dnra/" {
+		t.Errorf("Expected both forms to request the punycode host, but got %v and %v", requestURL(urls[0]), requestURL(urls[1]))
+	}
+}
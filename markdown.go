@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	fencePattern         = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	referencePattern     = regexp.MustCompile(`^ {0,3}\[[^\]^][^\]]*\]:[ \t]*`) // not footnotes, like [^1]:
+	markdownAutoPattern  = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9+.-]*:[^<>\s]+)>`)
+	markdownTitlePattern = regexp.MustCompile(`^[ \t]+("[^"]*"|'[^']*'|\([^)]*\))`)
+	inlineLinkStart      = []byte("](")
+)
+
+// extractMarkdownURLs finds URLs in inline links and images, autolinks,
+// reference definitions and prose. Code is skipped unless includeCode is set.
+// Relative link destinations are resolved against base, the URL of the
+// document, or skipped when it's nil.
+func extractMarkdownURLs(source io.Reader, includeCode bool, base *url.URL) ([]match, error) {
+	var matches []match
+
+	found := func(rawURL string, lineNo, column int) {
+		if u, ok := resolveURL(rawURL, base); ok {
+			matches = append(matches, match{
+				URL:      u,
+				location: location{Line: lineNo, Column: column, Text: rawURL},
+			})
+		}
+	}
+
+	fence := ""
+	lineNo := 0
+	lines := newLineReader(source)
+	for lines.Scan() {
+		lineNo++
+		line := lines.Line()
+
+		if open := fencePattern.FindStringSubmatch(line); open != nil {
+			if len(fence) == 0 {
+				fence = open[1]
+				continue
+			}
+
+			if open[1][0] == fence[0] && len(open[1]) >= len(fence) && len(strings.TrimSpace(line[len(open[0]):])) == 0 {
+				fence = ""
+				continue
+			}
+		}
+
+		if len(fence) > 0 && !includeCode {
+			continue
+		}
+
+		// the line is blanked in place, so a long line with many links is
+		// only copied once
+		buf := []byte(line)
+
+		if includeCode {
+			blankBackticks(buf)
+		} else {
+			blankCodeSpans(buf)
+		}
+
+		// the matched link syntax is blanked out so the remaining prose can
+		// be scanned like plain text without finding the same URL twice
+		if ref := referencePattern.Find(buf); len(ref) > 0 {
+			dest, destStart, end := linkDestination(buf, len(ref))
+			found(dest, lineNo, destStart+1)
+			blank(buf, 0, end)
+		}
+
+		for _, loc := range markdownAutoPattern.FindAllSubmatchIndex(buf, -1) {
+			found(string(buf[loc[2]:loc[3]]), lineNo, loc[2]+1)
+			blank(buf, loc[0], loc[1])
+		}
+
+		for from := 0; ; {
+			i := bytes.Index(buf[from:], inlineLinkStart)
+			if i == -1 {
+				break
+			}
+
+			start := from + i
+			dest, destStart, end := linkDestination(buf, start+2)
+			if end < len(buf) && buf[end] == ')' {
+				end++
+			}
+
+			found(dest, lineNo, destStart+1)
+			blank(buf, start, end)
+			from = end
+		}
+
+		for _, f := range proseFields(string(buf)) {
+			if u, ok := parseURL(f.text); ok {
+				matches = append(matches, match{
+					URL:      urlString(u),
+					location: location{Line: lineNo, Column: f.column, Text: f.text},
+				})
+			}
+		}
+	}
+
+	return matches, lines.Err()
+}
+
+// lineReader reads a stream line by line like bufio.Scanner, but without a
+// limit on the length of a line
+type lineReader struct {
+	r    *bufio.Reader
+	line string
+	err  error
+	done bool
+}
+
+func newLineReader(source io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(source)}
+}
+
+// Scan advances to the next line, returning false at the end of the stream
+// or on an error
+func (l *lineReader) Scan() bool {
+	if l.done {
+		return false
+	}
+
+	line, err := l.r.ReadString('\n')
+	if err != nil {
+		l.done = true
+		if err != io.EOF {
+			l.err = err
+		}
+
+		if len(line) == 0 {
+			return false
+		}
+	}
+
+	l.line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	return true
+}
+
+// Line returns the line read by the last call to Scan, without its line ending
+func (l *lineReader) Line() string {
+	return l.line
+}
+
+// Err returns the first error reading the stream, other than io.EOF
+func (l *lineReader) Err() error {
+	return l.err
+}
+
+// linkDestination parses a link destination and optional title starting at
+// line[start:], returning the destination, the index it starts at and the
+// index just past the title
+func linkDestination(line []byte, start int) (string, int, int) {
+	i := start
+	for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+
+	var dest string
+	destStart := i
+	if i < len(line) && line[i] == '<' {
+		end := bytes.IndexByte(line[i:], '>')
+		if end == -1 {
+			return "", i, len(line)
+		}
+
+		destStart = i + 1
+		dest = string(line[destStart : i+end])
+		i += end + 1
+	} else {
+		// parentheses are allowed in a destination as long as they're balanced
+		depth := 0
+		end := i
+		for ; end < len(line); end++ {
+			c := line[end]
+			if c == ' ' || c == '\t' || (c == ')' && depth == 0) {
+				break
+			}
+
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			}
+		}
+
+		dest = string(line[i:end])
+		i = end
+	}
+
+	if title := markdownTitlePattern.Find(line[i:]); len(title) > 0 {
+		i += len(title)
+	}
+
+	for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+
+	return dest, destStart, i
+}
+
+// blankCodeSpans replaces inline code spans with spaces, keeping columns intact
+func blankCodeSpans(line []byte) {
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+
+		run := 1
+		for i+run < len(line) && line[i+run] == '`' {
+			run++
+		}
+
+		closing := findBacktickRun(line, i+run, run)
+		if closing == -1 {
+			i += run
+			continue
+		}
+
+		blank(line, i, closing+run)
+		i = closing + run
+	}
+}
+
+// blankBackticks replaces the backticks of code spans with spaces, leaving
+// the code to be scanned
+func blankBackticks(line []byte) {
+	for i, c := range line {
+		if c == '`' {
+			line[i] = ' '
+		}
+	}
+}
+
+// findBacktickRun returns the index of the next run of exactly n backticks at or after start
+func findBacktickRun(line []byte, start, n int) int {
+	for i := start; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+
+		run := 1
+		for i+run < len(line) && line[i+run] == '`' {
+			run++
+		}
+
+		if run == n {
+			return i
+		}
+
+		i += run
+	}
+
+	return -1
+}
+
+// blank replaces line[start:end] with spaces, in place
+func blank(line []byte, start, end int) {
+	for i := start; i < end; i++ {
+		line[i] = ' '
+	}
+}
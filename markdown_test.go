@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestExtractMarkdownURLs(t *testing.T) {
+	examples := map[string][]string{
+		"[text](https://example.com/page)":                        []string{"https://example.com/page"},
+		"![logo](https://example.com/logo.png)":                   []string{"https://example.com/logo.png"},
+		`[text](https://example.com/page "Page title")`:           []string{"https://example.com/page"},
+		"[text](<https://example.com/with space>)":                []string{"https://example.com/with%20space"},
+		"[wiki](https://en.wikipedia.org/wiki/Go_(game)) is fun":  []string{"https://en.wikipedia.org/wiki/Go_(game)"},
+		"an autolink <https://example.com/auto>.":                 []string{"https://example.com/auto"},
+		"[id]: https://example.com/ref":                           []string{"https://example.com/ref"},
+		`[id]: <https://example.com/ref> 'Reference title'`:       []string{"https://example.com/ref"},
+		"prose mentions xkcd.com too":                             []string{"xkcd.com"},
+		"[relative](../guide.md) and [anchor](#install)":          []string{},
+		"inline `http://example.com/code` is skipped":             []string{},
+		"double ``code with ` http://example.com/code`` skipped":  []string{},
+		"```\nhttp://example.com/fenced\n```\nhttp://example.com": []string{"http://example.com"},
+		"~~~~ sh\ncurl http://example.com/fenced\n~~~\n~~~~":      []string{},
+	}
+
+	for source, expected := range examples {
+		actual := matchedURLs(extractMarkdownURLs(strings.NewReader(source), false, nil))
+
+		if !stringSlicesEqual(actual, expected) {
+			t.Errorf("Expected %v from '%v', but actually got %v", expected, source, actual)
+		}
+	}
+}
+
+func TestExtractMarkdownFootnotes(t *testing.T) {
+	base, _ := url.Parse("https://docs.example.com/")
+	source := "Text[^1] and more[^note].\n\n[^1]: See the docs for details.\n[^note]: Or https://example.com/faq\n[ref]: setup.md"
+	expected := []string{"https://example.com/faq", "https://docs.example.com/setup.md"}
+
+	if actual := matchedURLs(extractMarkdownURLs(strings.NewReader(source), false, base)); !stringSlicesEqual(actual, expected) {
+		t.Errorf("Expected footnotes not to be reference definitions, %v, but got %v", expected, actual)
+	}
+}
+
+func TestExtractMarkdownURLsIncludingCode(t *testing.T) {
+	source := "inline `http://example.com/span`\n```\nhttp://example.com/fenced\n```"
+	expected := []string{"http://example.com/span", "http://example.com/fenced"}
+
+	if actual := matchedURLs(extractMarkdownURLs(strings.NewReader(source), true, nil)); !stringSlicesEqual(actual, expected) {
+		t.Errorf("Expected %v, but actually got %v", expected, actual)
+	}
+}
+
+func TestExtractMarkdownURLsLocations(t *testing.T) {
+	source := "# Title\n\nSee [the docs](https://example.com/docs).\n\n[ref]: <https://example.com/ref>"
+	expected := []location{
+		{Line: 3, Column: 16, Text: "https://example.com/docs"},
+		{Line: 5, Column: 9, Text: "https://example.com/ref"},
+	}
+
+	matches, err := extractMarkdownURLs(strings.NewReader(source), false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v matches, but got %v", len(expected), matches)
+	}
+
+	for i, m := range matches {
+		if m.location != expected[i] {
+			t.Errorf("Expected %v to be found at %+v, but got %+v", m.URL, expected[i], m.location)
+		}
+	}
+}
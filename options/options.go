@@ -2,9 +2,17 @@ package options
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/jmks/urlstat/glob"
+	"github.com/jmks/urlstat/hosts"
 )
 
 // Options parsed at the command line
@@ -12,22 +20,108 @@ type Options struct {
 	list      *bool
 	ok        *bool
 	notOk     *bool
+	redirects *bool
+	permanent *bool
+	errors    *string
+	failOn    *string
+	stream    *bool
+	format    *string
+	include   stringList
+	exclude   stringList
+	mdCode    *bool
+	binary    *bool
+	workers   *int
+	perHost   *int
+	method    *string
+	timeout   *time.Duration
+	deadline  *time.Duration
+	retries   *int
+	backoff   *time.Duration
+	stdin     *string
+	tldFile   *string
+	tldMode   *string
+	pslFile   *string
+	groupBy   *string
+	limitBy   *string
+	allowTLD  *string
+	allowHost *string
+	special   *bool
+	mixed     *bool
+	excludeIP *string
+	baseURL   *string
+	root      *string
+	stdinData []byte
+	flags     *flag.FlagSet
+	flagErr   error
 	Filepaths []string
 }
 
+// StdinPath is the file argument that reads content from stdin
+const StdinPath = "-"
+
 // Parse arguments and flags and returns options configuration struct
-func Parse() (opts Options) {
-	opts.list = flag.Bool("list", false, "only list URIs found in files (i.e. no status check)")
-	opts.ok = flag.Bool("ok", false, "only list URIs with HTTP status code 200 OK")
-	opts.notOk = flag.Bool("no-ok", false, "list URIs with HTTP status code other than 200 OK (overrides --ok)")
+func Parse() Options {
+	return parse(flag.CommandLine, os.Args[1:])
+}
+
+// ParseArgs parses args, without the program name, like Parse does the
+// command line, except that flags which can't be parsed make the options
+// invalid, for PrintError to report, instead of exiting
+func ParseArgs(args []string) Options {
+	flags := flag.NewFlagSet("urlstat", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	return parse(flags, args)
+}
 
-	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage of URLstat: urlstat [options] files...")
-		fmt.Fprintln(os.Stderr, "Options:")
-		flag.PrintDefaults()
+func parse(flags *flag.FlagSet, args []string) (opts Options) {
+	opts.flags = flags
+	opts.list = flags.Bool("list", false, "only list URIs found in files (i.e. no status check)")
+	opts.ok = flags.Bool("ok", false, "only list URIs with HTTP status code 200 OK")
+	opts.notOk = flags.Bool("no-ok", false, "list URIs with HTTP status code other than 200 OK (overrides --ok)")
+	opts.redirects = flags.Bool("redirects", false, "only list URIs that redirect, with each hop of the redirect chain")
+	opts.permanent = flags.Bool("warn-permanent", false, "warn about URIs that redirect permanently (301 or 308) and should be updated")
+	opts.errors = flags.String("errors", "", "only list URIs that failed with these comma separated error categories: "+strings.Join(errorCategories, ", ")+" or all")
+	opts.failOn = flags.String("fail-on", strings.Join(defaultFailOn, ","), "comma separated classes of URIs that make urlstat exit 1: 404, 4xx (including 404), 5xx, 3xx, other (non-200 statuses), redirect (redirected to 200 OK), an error category or all")
+	opts.baseURL = flags.String("base-url", "", "URL the files under -root are published at, e.g. https://docs.example.com/, to check relative links in HTML and Markdown files")
+	opts.root = flags.String("root", ".", "directory published at -base-url, so site/guide/index.html under -root site is at <base-url>/guide/index.html")
+	opts.excludeIP = flags.String("exclude-ip", "", "skip URIs with IP address hosts in these comma separated ranges: "+strings.Join(hosts.Ranges, ", ")+" or all")
+	opts.mixed = flags.Bool("warn-mixed-script", false, "warn about URIs with hosts that mix scripts, like Cyrillic and Latin, a common trick to imitate another host")
+	opts.mdCode = flags.Bool("md-code", false, "extract URIs from fenced code blocks and code spans in Markdown files")
+	opts.binary = flags.Bool("binary", false, "scan files that look binary instead of skipping them")
+	opts.workers = flags.Int("concurrency", 20, "maximum number of URIs to check at once")
+	opts.perHost = flags.Int("per-host", 4, "maximum number of URIs to check at once on a single host (0 for no limit)")
+	opts.method = flags.String("method", "auto", "HTTP method used to check URIs: head, get or auto (HEAD, retrying with GET when rejected)")
+	opts.timeout = flags.Duration("timeout", 10*time.Second, "maximum time for each request (0 for no limit)")
+	opts.deadline = flags.Duration("deadline", 0, "maximum time for all status checks, after which remaining URIs fail (0 for no limit)")
+	opts.retries = flags.Int("retries", 2, "number of times to retry a URI after a timeout, a refused or reset connection, or a 429 or 5xx response")
+	opts.backoff = flags.Duration("backoff", 500*time.Millisecond, "wait before the first retry, doubling for each retry after, or 0 to retry at once (a Retry-After header takes precedence)")
+	opts.format = flags.String("format", "text", "output format: text, json (a single document with a summary), ndjson (an object per URI, as each check completes), junit (XML with a testsuite per file) or sarif (2.1.0, for code scanning)")
+	opts.stream = flags.Bool("stream", false, "print each URI as its check completes instead of grouping by file")
+	opts.tldFile = flags.String("tld-file", "", "file of known TLDs in the format of IANA's tlds-alpha-by-domain.txt (defaults to the list cached by 'urlstat tld update', or the built-in list)")
+	opts.tldMode = flags.String("tld-mode", "iana", "how hosts are recognized: iana (ends in a known TLD) or publicsuffix (is under a public suffix, so 'co.uk' alone isn't a host)")
+	opts.pslFile = flags.String("psl-file", "", "Public Suffix List file for -tld-mode publicsuffix and grouping by domain (defaults to the list cached by 'urlstat tld update -list publicsuffix')")
+	opts.groupBy = flags.String("group-by", "file", "how the text report is grouped: file, or domain (the registrable domain, like example.co.uk)")
+	opts.limitBy = flags.String("limit-by", "host", "what -per-host limits concurrent checks to: host, or domain (the registrable domain, so a.example.com and b.example.com share a limit)")
+	opts.allowTLD = flags.String("allow-tld", "", "comma separated TLDs to accept besides the known ones, e.g. corp,internal")
+	opts.allowHost = flags.String("allow-host", "", "comma separated hostnames to accept exactly, e.g. jenkins,wiki.corp")
+	opts.special = flags.Bool("special-use", false, "accept the special-use names .local, .test, .localhost and .invalid")
+	opts.stdin = flags.String("stdin", "paths", "how stdin is read when no files are given: paths (a file or directory per line), content (scanned for URIs, like a '-' argument) or auto (paths when every line is an existing path, content otherwise)")
+
+	flags.Var(&opts.include, "include", "when scanning directories, only scan files matching this glob, e.g. '*.md' or 'docs/**/*.html' (repeatable)")
+	flags.Var(&opts.exclude, "exclude", "when scanning directories, skip files and directories matching this glob, e.g. 'vendor/**' (repeatable)")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of URLstat: urlstat [options] files or directories... ('-' reads content from stdin)")
+		fmt.Fprintln(flags.Output(), "   or: urlstat tld update [-url url] [-file path]")
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
 	}
 
-	flag.Parse()
+	opts.flagErr = flags.Parse(args)
+	if opts.flagErr != nil {
+		return opts
+	}
 
 	opts.populateFilepaths()
 
@@ -35,38 +129,192 @@ func Parse() (opts Options) {
 }
 
 func (opts *Options) populateFilepaths() {
-	if flag.Parsed() {
-		opts.Filepaths = flag.Args()
-	} else {
-		opts.Filepaths = os.Args[1:]
+	opts.Filepaths = opts.flags.Args()
+
+	if len(opts.Filepaths) > 0 || !validStdinModes[*opts.stdin] {
+		return
 	}
 
-	if len(opts.Filepaths) == 0 {
-		inStat, _ := os.Stdin.Stat()
+	inStat, _ := os.Stdin.Stat()
+	if (inStat.Mode() & os.ModeCharDevice) != 0 {
+		return
+	}
 
-		if (inStat.Mode() & os.ModeCharDevice) == 0 {
-			stdinScanner := bufio.NewScanner(os.Stdin)
+	switch *opts.stdin {
+	case "content":
+		opts.Filepaths = []string{StdinPath}
+	case "auto":
+		opts.stdinData, _ = io.ReadAll(os.Stdin)
 
-			for stdinScanner.Scan() {
-				opts.Filepaths = append(opts.Filepaths, stdinScanner.Text())
-			}
+		if paths := existingPaths(opts.stdinData); len(paths) > 0 {
+			opts.Filepaths = paths
+		} else {
+			opts.Filepaths = []string{StdinPath}
 		}
+	default:
+		stdinScanner := bufio.NewScanner(os.Stdin)
+
+		for stdinScanner.Scan() {
+			opts.Filepaths = append(opts.Filepaths, stdinScanner.Text())
+		}
+	}
+}
+
+// existingPaths returns the lines of data when every non-blank line is the
+// path of an existing file or directory, or nil otherwise
+func existingPaths(data []byte) []string {
+	var paths []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		if _, err := os.Stat(line); err != nil {
+			return nil
+		}
+
+		paths = append(paths, line)
+	}
+
+	if scanner.Err() != nil {
+		return nil
 	}
+
+	return paths
+}
+
+// Stdin returns the reader of content from stdin, which may have already been
+// read while detecting whether it's a list of paths
+func (opts Options) Stdin() io.Reader {
+	if opts.stdinData != nil {
+		return bytes.NewReader(opts.stdinData)
+	}
+
+	return os.Stdin
 }
 
 // IsValid returns whether Options is valid
 func (opts Options) IsValid() bool {
-	return len(opts.Filepaths) > 0
+	return opts.flagErr == nil && len(opts.Filepaths) > 0 && *opts.workers > 0 && *opts.perHost >= 0 && validMethods[*opts.method] && validFormats[*opts.format] &&
+		len(opts.unknownErrorCategories()) == 0 && len(opts.unknownFailOnClasses()) == 0 && len(opts.invalidGlobs()) == 0 &&
+		len(opts.unknownIPRanges()) == 0 && opts.isBaseURLValid() &&
+		validStdinModes[*opts.stdin] && validTLDModes[*opts.tldMode] && validGroupBys[*opts.groupBy] && validLimitBys[*opts.limitBy] &&
+		*opts.timeout >= 0 && *opts.deadline >= 0 && *opts.retries >= 0 && *opts.backoff >= 0 &&
+		(!*opts.list || validListFormats[*opts.format])
 }
 
-// PrintError prints reason Options was invalid and usage info to stderr
-func (opts Options) PrintError() {
+var validMethods = map[string]bool{"head": true, "get": true, "auto": true}
+
+var validTLDModes = map[string]bool{"iana": true, "publicsuffix": true}
+
+var validGroupBys = map[string]bool{"file": true, "domain": true}
+
+var validLimitBys = map[string]bool{"host": true, "domain": true}
+
+var validStdinModes = map[string]bool{"paths": true, "content": true, "auto": true}
+
+var validFormats = map[string]bool{"text": true, "json": true, "ndjson": true, "junit": true, "sarif": true}
+
+// validListFormats are the formats -list can print links in, since the others
+// report on checks
+var validListFormats = map[string]bool{"text": true, "json": true, "ndjson": true}
+
+// errorCategories are the categories failed checks are classified into
+var errorCategories = []string{"dns", "connect", "tls", "timeout", "protocol", "invalid-url"}
+
+// statusClasses are the classes of URIs that didn't fail with an error, but
+// aren't simply 200 OK either
+var statusClasses = []string{"404", "4xx", "5xx", "3xx", "other", "redirect"}
+
+// defaultFailOn is every failure class, except URIs that redirect to 200 OK
+var defaultFailOn = append([]string{"4xx", "5xx", "3xx", "other"}, errorCategories...)
+
+// PrintError prints reason Options was invalid and usage info to w
+func (opts Options) PrintError(w io.Writer) {
+	opts.flags.SetOutput(w)
+
+	if opts.flagErr != nil {
+		if opts.flagErr != flag.ErrHelp {
+			fmt.Fprintln(w, opts.flagErr)
+		}
+
+		opts.flags.Usage()
+		return
+	}
+
 	if len(opts.Filepaths) == 0 {
-		fmt.Fprintln(os.Stderr, "No files to scan")
+		fmt.Fprintln(w, "No files to scan")
+	}
+
+	if !validTLDModes[*opts.tldMode] {
+		fmt.Fprintf(w, "Unknown -tld-mode '%v'\n", *opts.tldMode)
+	}
+
+	if !validGroupBys[*opts.groupBy] {
+		fmt.Fprintf(w, "Unknown -group-by '%v'\n", *opts.groupBy)
 	}
 
-	fmt.Fprintln(os.Stderr, "")
-	flag.Usage()
+	if !validLimitBys[*opts.limitBy] {
+		fmt.Fprintf(w, "Unknown -limit-by '%v'\n", *opts.limitBy)
+	}
+
+	if !validStdinModes[*opts.stdin] {
+		fmt.Fprintf(w, "Unknown -stdin mode '%v'\n", *opts.stdin)
+	}
+
+	if *opts.workers < 1 {
+		fmt.Fprintln(w, "-concurrency must be at least 1")
+	}
+
+	if *opts.perHost < 0 {
+		fmt.Fprintln(w, "-per-host must not be negative")
+	}
+
+	if !validMethods[*opts.method] {
+		fmt.Fprintf(w, "Unknown -method '%v'\n", *opts.method)
+	}
+
+	if !validFormats[*opts.format] {
+		fmt.Fprintf(w, "Unknown -format '%v'\n", *opts.format)
+	}
+
+	if *opts.list && validFormats[*opts.format] && !validListFormats[*opts.format] {
+		fmt.Fprintf(w, "-list can't be used with -format '%v', only text, json or ndjson\n", *opts.format)
+	}
+
+	for _, category := range opts.unknownErrorCategories() {
+		fmt.Fprintf(w, "Unknown -errors category '%v'\n", category)
+	}
+
+	for _, pattern := range opts.invalidGlobs() {
+		fmt.Fprintf(w, "Invalid glob '%v'\n", pattern)
+	}
+
+	if !opts.isBaseURLValid() {
+		fmt.Fprintf(w, "-base-url '%v' must be an absolute http or https URL\n", *opts.baseURL)
+	}
+
+	for _, r := range opts.unknownIPRanges() {
+		fmt.Fprintf(w, "Unknown -exclude-ip range '%v'\n", r)
+	}
+
+	for _, class := range opts.unknownFailOnClasses() {
+		fmt.Fprintf(w, "Unknown -fail-on class '%v'\n", class)
+	}
+
+	if *opts.timeout < 0 || *opts.deadline < 0 || *opts.backoff < 0 {
+		fmt.Fprintln(w, "-timeout, -deadline and -backoff must not be negative")
+	}
+
+	if *opts.retries < 0 {
+		fmt.Fprintln(w, "-retries must not be negative")
+	}
+
+	fmt.Fprintln(w, "")
+	opts.flags.Usage()
 }
 
 // ListOnly returns bool indicating if the found URIs should be printed
@@ -74,6 +322,265 @@ func (opts Options) ListOnly() bool {
 	return *opts.list
 }
 
+// TLDFile returns the path of the file of known TLDs, if one was given
+func (opts Options) TLDFile() string {
+	return *opts.tldFile
+}
+
+// TLDMode returns how hosts are recognized: iana or publicsuffix
+func (opts Options) TLDMode() string {
+	return *opts.tldMode
+}
+
+// PublicSuffixFile returns the path of the Public Suffix List file, if one was given
+func (opts Options) PublicSuffixFile() string {
+	return *opts.pslFile
+}
+
+// UsesPublicSuffixes returns bool indicating if the Public Suffix List is needed
+func (opts Options) UsesPublicSuffixes() bool {
+	return *opts.tldMode == "publicsuffix" || *opts.groupBy == "domain" || *opts.limitBy == "domain" || len(*opts.pslFile) > 0
+}
+
+// GroupBy returns how the text report is grouped: file or domain
+func (opts Options) GroupBy() string {
+	return *opts.groupBy
+}
+
+// LimitBy returns what -per-host limits concurrent checks to: host or domain
+func (opts Options) LimitBy() string {
+	return *opts.limitBy
+}
+
+// BaseURL returns the URL the files under Root are published at, or nil when
+// relative links shouldn't be checked. Its path always ends in a slash.
+func (opts Options) BaseURL() *url.URL {
+	if len(*opts.baseURL) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(*opts.baseURL)
+	if err != nil {
+		return nil
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		u.RawPath = ""
+	}
+
+	return u
+}
+
+// Root returns the directory published at BaseURL
+func (opts Options) Root() string {
+	return *opts.root
+}
+
+func (opts Options) isBaseURLValid() bool {
+	if len(*opts.baseURL) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(*opts.baseURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0
+}
+
+// ExcludedIPRanges returns the ranges of IP address hosts to skip
+func (opts Options) ExcludedIPRanges() []string {
+	return splitList(*opts.excludeIP)
+}
+
+func (opts Options) unknownIPRanges() []string {
+	return unknown(opts.ExcludedIPRanges(), hosts.Ranges)
+}
+
+// AllowedTLDs returns the TLDs to accept besides the known ones
+func (opts Options) AllowedTLDs() []string {
+	return splitList(*opts.allowTLD)
+}
+
+// AllowedHosts returns the hostnames to accept exactly
+func (opts Options) AllowedHosts() []string {
+	return splitList(*opts.allowHost)
+}
+
+// SpecialUse returns bool indicating if special-use names like .test should be accepted
+func (opts Options) SpecialUse() bool {
+	return *opts.special
+}
+
+// Include returns the globs files in directories must match to be scanned
+func (opts Options) Include() []string {
+	return opts.include
+}
+
+// Exclude returns the globs of files and directories to skip in directories
+func (opts Options) Exclude() []string {
+	return opts.exclude
+}
+
+func (opts Options) invalidGlobs() []string {
+	var invalid []string
+	for _, pattern := range append(opts.Include(), opts.Exclude()...) {
+		if !glob.IsValid(pattern) {
+			invalid = append(invalid, pattern)
+		}
+	}
+
+	return invalid
+}
+
+// Format returns the output format: text, json, ndjson, junit or sarif
+func (opts Options) Format() string {
+	return *opts.format
+}
+
+// Stream returns bool indicating if statuses should be printed as they complete
+func (opts Options) Stream() bool {
+	return *opts.stream
+}
+
+// MarkdownCode returns bool indicating if code in Markdown files should be scanned
+func (opts Options) MarkdownCode() bool {
+	return *opts.mdCode
+}
+
+// ScanBinary returns bool indicating if files that look binary should be scanned
+func (opts Options) ScanBinary() bool {
+	return *opts.binary
+}
+
+// Concurrency returns the maximum number of status checks to run at once
+func (opts Options) Concurrency() int {
+	return *opts.workers
+}
+
+// PerHost returns the maximum number of status checks to run at once against
+// a single host, or 0 for no limit
+func (opts Options) PerHost() int {
+	return *opts.perHost
+}
+
+// Method returns the HTTP method used to check URIs: head, get or auto
+func (opts Options) Method() string {
+	return *opts.method
+}
+
+// RedirectsOnly returns bool indicating if only URIs that redirect should be printed
+func (opts Options) RedirectsOnly() bool {
+	return *opts.redirects
+}
+
+// WarnPermanent returns bool indicating if permanent redirects should be printed as warnings
+func (opts Options) WarnPermanent() bool {
+	return *opts.permanent
+}
+
+// WarnMixedScript returns bool indicating if hosts that mix scripts should be printed as warnings
+func (opts Options) WarnMixedScript() bool {
+	return *opts.mixed
+}
+
+// Timeout returns the maximum time for each request, or 0 for no limit
+func (opts Options) Timeout() time.Duration {
+	return *opts.timeout
+}
+
+// Deadline returns the maximum time for all status checks, or 0 for no limit
+func (opts Options) Deadline() time.Duration {
+	return *opts.deadline
+}
+
+// Retries returns the number of times to retry a failed request
+func (opts Options) Retries() int {
+	return *opts.retries
+}
+
+// Backoff returns the wait before the first retry
+func (opts Options) Backoff() time.Duration {
+	return *opts.backoff
+}
+
+// ErrorsOnly returns bool indicating if only URIs that failed with an error should be printed
+func (opts Options) ErrorsOnly() bool {
+	return len(opts.errorFilter()) > 0
+}
+
+// IsErrorListable returns true when URIs that failed with an error in category should be printed
+func (opts Options) IsErrorListable(category string) bool {
+	filter := opts.errorFilter()
+	if len(filter) == 0 {
+		return opts.IsNotOkListable()
+	}
+
+	for _, c := range filter {
+		if c == category || c == "all" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FailsOn returns true when a URI of the failure class should make urlstat exit
+// with an error. The empty class, for 200 OK, never fails.
+func (opts Options) FailsOn(class string) bool {
+	if len(class) == 0 {
+		return false
+	}
+
+	for _, c := range splitList(*opts.failOn) {
+		if c == class || c == "all" || (c == "4xx" && class == "404") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (opts Options) errorFilter() []string {
+	return splitList(*opts.errors)
+}
+
+func (opts Options) unknownErrorCategories() []string {
+	return unknown(opts.errorFilter(), errorCategories)
+}
+
+func (opts Options) unknownFailOnClasses() []string {
+	return unknown(splitList(*opts.failOn), append(statusClasses, errorCategories...))
+}
+
+// splitList splits a comma separated flag value into lowercase items
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, strings.ToLower(item))
+		}
+	}
+
+	return list
+}
+
+// unknown returns the items of list that are neither in known nor "all"
+func unknown(list, known []string) []string {
+	var found []string
+
+	for _, item := range list {
+		ok := item == "all"
+		for _, k := range known {
+			ok = ok || item == k
+		}
+
+		if !ok {
+			found = append(found, item)
+		}
+	}
+
+	return found
+}
+
 // IsOkListable returns true when OK responses should be printed
 func (opts Options) IsOkListable() bool {
 	if *opts.ok || *opts.notOk {
@@ -91,3 +598,16 @@ func (opts Options) IsNotOkListable() bool {
 
 	return true
 }
+
+// stringList is a flag that may be given more than once
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+// Set appends value to the list
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jmks/urlstat/options"
+)
+
+// occurrence is a single location of a checked link
+type occurrence struct {
+	location
+	result
+}
+
+// printReport waits for every check to complete, then prints results grouped
+// by file and ordered by line number, or grouped by registrable domain and
+// ordered by location. It returns the first error writing to w.
+func printReport(w io.Writer, src <-chan result, opts options.Options) error {
+	var results []result
+	for r := range src {
+		if isResultPrintable(r, opts) {
+			results = append(results, r)
+		}
+	}
+
+	groups := groupByFile(results)
+	if opts.GroupBy() == "domain" {
+		groups = groupByDomain(results)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := &errWriter{w: w}
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+
+		fmt.Fprintln(out, name)
+		for _, o := range groups[name] {
+			where := fmt.Sprintf("%v:%v", o.Line, o.Column)
+			if opts.GroupBy() == "domain" {
+				where = o.location.String()
+			}
+
+			fmt.Fprintf(out, "  %v %v : %v\n", where, statusText(o.result, opts), urlText(o.result))
+			if opts.RedirectsOnly() {
+				printRedirects(out, o.Redirects, "      ")
+			}
+		}
+	}
+
+	return out.err
+}
+
+// groupByFile splits results into occurrences keyed by file path, each ordered
+// by line and column
+func groupByFile(results []result) map[string][]occurrence {
+	byFile := make(map[string][]occurrence)
+
+	for _, r := range results {
+		for _, loc := range r.Locations {
+			byFile[loc.Filepath] = append(byFile[loc.Filepath], occurrence{location: loc, result: r})
+		}
+	}
+
+	for _, occurrences := range byFile {
+		sort.Slice(occurrences, func(i, j int) bool {
+			return locationLess(occurrences[i].location, occurrences[j].location)
+		})
+	}
+
+	return byFile
+}
+
+// groupByDomain splits results into occurrences keyed by the registrable
+// domain of their URL, each ordered by location
+func groupByDomain(results []result) map[string][]occurrence {
+	byDomain := make(map[string][]occurrence)
+
+	for _, r := range results {
+		domain := domainOf(r.URL)
+		for _, loc := range r.Locations {
+			byDomain[domain] = append(byDomain[domain], occurrence{location: loc, result: r})
+		}
+	}
+
+	for _, occurrences := range byDomain {
+		sort.Slice(occurrences, func(i, j int) bool {
+			return locationLess(occurrences[i].location, occurrences[j].location)
+		})
+	}
+
+	return byDomain
+}
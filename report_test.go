@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmks/urlstat/tld"
+)
+
+func TestGroupByFile(t *testing.T) {
+	results := []result{
+		{link: link{URL: "xkcd.com", Locations: []location{
+			{Filepath: "b.md", Line: 7, Column: 1},
+			{Filepath: "a.md", Line: 12, Column: 3},
+		}}, StatusCode: 200},
+		{link: link{URL: "http://example.com", Locations: []location{
+			{Filepath: "a.md", Line: 2, Column: 5},
+		}}, StatusCode: 404},
+	}
+
+	byFile := groupByFile(results)
+
+	if len(byFile) != 2 {
+		t.Fatalf("Expected results for 2 files, but got %v", byFile)
+	}
+
+	a := byFile["a.md"]
+	if len(a) != 2 || a[0].URL != "http://example.com" || a[1].URL != "xkcd.com" {
+		t.Errorf("Expected a.md ordered by line, but got %+v", a)
+	}
+
+	b := byFile["b.md"]
+	if len(b) != 1 || b[0].URL != "xkcd.com" || b[0].Line != 7 {
+		t.Errorf("Expected xkcd.com in b.md at line 7, but got %+v", b)
+	}
+}
+
+func TestGroupByDomain(t *testing.T) {
+	list, err := tld.ParsePublicSuffixList(strings.NewReader(publicSuffixList))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tld.UsePublicSuffixList(list)
+	defer tld.UsePublicSuffixList(nil)
+
+	results := []result{
+		{link: link{URL: "https://www.example.co.uk/a", Locations: []location{{Filepath: "b.md", Line: 1, Column: 1}}}},
+		{link: link{URL: "example.co.uk/b", Locations: []location{{Filepath: "a.md", Line: 9, Column: 1}}}},
+		{link: link{URL: "http://foo.blogspot.com:8080/", Locations: []location{{Filepath: "a.md", Line: 2, Column: 1}}}},
+		{link: link{URL: "http://bar.blogspot.com/", Locations: []location{{Filepath: "a.md", Line: 3, Column: 1}}}},
+		{link: link{URL: "http://127.0.0.1:1/a", Locations: []location{{Filepath: "a.md", Line: 4, Column: 1}}}},
+		{link: link{URL: "http://10.0.0.1:1/b", Locations: []location{{Filepath: "a.md", Line: 5, Column: 1}}}},
+		{link: link{URL: "http://192.168.5.1/c", Locations: []location{{Filepath: "a.md", Line: 6, Column: 1}}}},
+		{link: link{URL: "http://[::1]:9000/d", Locations: []location{{Filepath: "a.md", Line: 7, Column: 1}}}},
+	}
+
+	byDomain := groupByDomain(results)
+
+	if len(byDomain) != 7 {
+		t.Fatalf("Expected results for 7 domains, but got %v", byDomain)
+	}
+
+	for _, addr := range []string{"127.0.0.1", "10.0.0.1", "192.168.5.1", "::1"} {
+		if len(byDomain[addr]) != 1 {
+			t.Errorf("Expected IP address %v to be its own group, but got %v", addr, byDomain)
+		}
+	}
+
+	uk := byDomain["example.co.uk"]
+	if len(uk) != 2 || uk[0].URL != "example.co.uk/b" || uk[1].URL != "https://www.example.co.uk/a" {
+		t.Errorf("Expected example.co.uk ordered by location, but got %+v", uk)
+	}
+
+	if len(byDomain["foo.blogspot.com"]) != 1 || len(byDomain["bar.blogspot.com"]) != 1 {
+		t.Errorf("Expected private suffixes to separate domains, but got %v", byDomain)
+	}
+
+	if limitKey("http://a.example.co.uk:8080/", "domain") != "example.co.uk" || limitKey("http://a.example.co.uk:8080/", "host") != "a.example.co.uk:8080" {
+		t.Error("Expected checks to be limited by domain or host")
+	}
+
+	if limitKey("http://127.0.0.1:1/a", "domain") == limitKey("http://10.0.0.1:1/b", "domain") {
+		t.Error("Expected different IP addresses not to share a limit")
+	}
+}
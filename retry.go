@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxBackoff caps the exponential backoff between retries
+	maxBackoff = 30 * time.Second
+	// maxRetryAfter is the longest Retry-After honored; responses asking for
+	// a longer wait are not retried
+	maxRetryAfter = time.Minute
+)
+
+// requestWithRetries requests reqURL, retrying transient network errors, 429 and 5xx
+// responses up to cfg.retries times with exponential backoff and jitter, or
+// after the wait the server asked for in Retry-After
+func requestWithRetries(ctx context.Context, cfg checkConfig, method, reqURL string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := request(ctx, cfg, method, reqURL)
+		if attempt >= cfg.retries || !isRetryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		wait := backoff(cfg.backoff, attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp, time.Now()); ok {
+				if after > maxRetryAfter {
+					return resp, err
+				}
+
+				wait = after
+			}
+
+			discard(resp)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func request(ctx context.Context, cfg checkConfig, method, reqURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.client().Do(req)
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return isTransient(err)
+	}
+
+	code := resp.StatusCode
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented && code != http.StatusHTTPVersionNotSupported)
+}
+
+// isTransient returns whether a failed request might succeed if tried again:
+// it timed out, the connection was refused or reset, or the DNS lookup failed
+// temporarily. Errors like a missing host or an untrusted certificate won't go
+// away by waiting.
+func isTransient(err error) bool {
+	category, _ := classifyError(err)
+
+	switch category {
+	case categoryTimeout:
+		return true
+	case categoryConnect:
+		return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+	case categoryDNS:
+		var dnsErr *net.DNSError
+		return errors.As(err, &dnsErr) && dnsErr.IsTemporary
+	default:
+		return false
+	}
+}
+
+// backoff returns the wait before retry number attempt+1: base doubled for
+// each previous attempt, with up to half of it taken off at random. A base of
+// 0 retries at once.
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	wait := base
+	for i := 0; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// retryAfter parses the Retry-After header, in either seconds or as an HTTP date
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if len(header) == 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(header); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
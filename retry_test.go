@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRequestWithRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&attempts, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	examples := []struct {
+		retries    int
+		statusCode int
+		attempts   int32
+	}{
+		{0, 503, 1},
+		{1, 429, 2},
+		{5, 200, 3},
+	}
+
+	for _, example := range examples {
+		atomic.StoreInt32(&attempts, 0)
+		cfg := checkConfig{retries: example.retries, backoff: time.Millisecond}
+
+		resp, err := requestWithRetries(context.Background(), cfg, http.MethodHead, server.URL)
+		if err != nil {
+			t.Fatalf("Expected no error with %v retries, but got %v", example.retries, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != example.statusCode || atomic.LoadInt32(&attempts) != example.attempts {
+			t.Errorf("Expected %v after %v attempts with %v retries, but got %v after %v", example.statusCode, example.attempts, example.retries, resp.StatusCode, attempts)
+		}
+	}
+}
+
+func TestRequestWithRetriesGivesUpOnLongRetryAfter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	resp, err := requestWithRetries(context.Background(), checkConfig{retries: 3}, http.MethodHead, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 429 || attempts != 1 {
+		t.Errorf("Expected a single 429, but got %v after %v attempts", resp.StatusCode, attempts)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	refused := &url.Error{Op: "Head", URL: "http://localhost:1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}
+	_, parseErr := url.Parse("http://[::1")
+
+	examples := []struct {
+		name     string
+		resp     *http.Response
+		err      error
+		expected bool
+	}{
+		{"timeout", nil, context.DeadlineExceeded, true},
+		{"connection refused", nil, refused, true},
+		{"connection reset", nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"temporary DNS failure", nil, &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}, true},
+		{"no such host", nil, &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, false},
+		{"unknown authority", nil, &url.Error{Op: "Head", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, false},
+		{"malformed URL", nil, parseErr, false},
+		{"503", &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true},
+		{"429", &http.Response{StatusCode: http.StatusTooManyRequests}, nil, true},
+		{"501", &http.Response{StatusCode: http.StatusNotImplemented}, nil, false},
+		{"404", &http.Response{StatusCode: http.StatusNotFound}, nil, false},
+	}
+
+	for _, example := range examples {
+		if actual := isRetryable(example.resp, example.err); actual != example.expected {
+			t.Errorf("Expected %v to be retryable %v, but got %v", example.name, example.expected, actual)
+		}
+	}
+}
+
+func TestRequestTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	if _, err := requestWithRetries(context.Background(), checkConfig{timeout: 20 * time.Millisecond}, http.MethodHead, server.URL); err == nil {
+		t.Errorf("Expected the request to time out")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := requestWithRetries(ctx, checkConfig{retries: 5, backoff: time.Millisecond}, http.MethodHead, server.URL); err == nil {
+		t.Errorf("Expected the request to fail after the deadline")
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected retries to stop at the deadline, but took %v", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	examples := []struct {
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Sat, 01 Oct 2016 12:00:30 GMT", 30 * time.Second, true},
+		{"Sat, 01 Oct 2016 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, example := range examples {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", example.header)
+
+		actual, ok := retryAfter(resp, now)
+		if actual != example.expected || ok != example.ok {
+			t.Errorf("Expected Retry-After '%v' to be %v (%v), but got %v (%v)", example.header, example.expected, example.ok, actual, ok)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		wait := backoff(time.Second, attempt)
+
+		max := time.Second << uint(attempt)
+		if max > maxBackoff {
+			max = maxBackoff
+		}
+
+		if wait < max/2 || wait > max {
+			t.Errorf("Expected attempt %v to wait between %v and %v, but got %v", attempt, max/2, max, wait)
+		}
+	}
+
+	if wait := backoff(0, 3); wait != 0 {
+		t.Errorf("Expected a backoff of 0 not to wait, but got %v", wait)
+	}
+}
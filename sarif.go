@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmks/urlstat/options"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// sarifRules has a rule for each failure class
+var sarifRules = []sarifRule{
+	newSARIFRule(classNotFound, "NotFound", "Link is not found (404)", "error"),
+	newSARIFRule(classClient, "ClientError", "Link responds with a client error (4xx)", "error"),
+	newSARIFRule(classServer, "ServerError", "Link responds with a server error (5xx)", "error"),
+	newSARIFRule(classUnfollowed, "UnfollowedRedirect", "Link responds with a redirect that could not be followed (3xx)", "error"),
+	newSARIFRule(classOther, "UnexpectedStatus", "Link responds with a status other than 200 OK", "warning"),
+	newSARIFRule(classRedirect, "Redirect", "Link redirects before responding with 200 OK", "note"),
+	newSARIFRule(categoryDNS, "DNSError", "Link host could not be resolved", "error"),
+	newSARIFRule(categoryConnect, "ConnectError", "Link host refused or could not be reached", "error"),
+	newSARIFRule(categoryTLS, "TLSError", "Link host has a TLS or certificate problem", "error"),
+	newSARIFRule(categoryTimeout, "Timeout", "Link timed out", "warning"),
+	newSARIFRule(categoryProtocol, "ProtocolError", "Link host responded with an invalid or looping HTTP response", "error"),
+	newSARIFRule(categoryInvalidURL, "InvalidURL", "Link is not a valid URL", "error"),
+}
+
+// sarifRuleIndex is the index of the rule for each class in sarifRules
+var sarifRuleIndex = func() map[string]int {
+	index := make(map[string]int, len(sarifRules))
+	for i, rule := range sarifRules {
+		index[rule.ID] = i
+	}
+
+	return index
+}()
+
+func newSARIFRule(class, name, description, level string) sarifRule {
+	return sarifRule{
+		ID:                   "urlstat/" + class,
+		Name:                 name,
+		ShortDescription:     sarifMessage{Text: description},
+		DefaultConfiguration: sarifConfiguration{Level: level},
+	}
+}
+
+// printSARIF waits for every check to complete, then writes a SARIF log with
+// a result for each occurrence of every printable result that isn't OK
+func printSARIF(w io.Writer, src <-chan result, printable func(result) bool) error {
+	var results []result
+	for r := range src {
+		if printable(r) && len(r.Class()) > 0 {
+			results = append(results, r)
+		}
+	}
+
+	byFile := groupByFile(results)
+
+	filepaths := make([]string, 0, len(byFile))
+	for path := range byFile {
+		filepaths = append(filepaths, path)
+	}
+	sort.Strings(filepaths)
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "urlstat",
+			InformationURI: "https://github.com/jmks/urlstat",
+			Rules:          sarifRules,
+		}},
+		ColumnKind: "utf16CodeUnits",
+		Results:    []sarifResult{},
+	}
+
+	for _, path := range filepaths {
+		lines := readLines(path)
+
+		for _, o := range byFile[path] {
+			o.Column = utf16Column(lines, o.Line, o.Column)
+
+			r, err := newSARIFResult(o)
+			if err != nil {
+				return err
+			}
+
+			run.Results = append(run.Results, r)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// readLines returns the lines of the file at path, or nil when it can't be
+// read again, like stdin
+func readLines(path string) []string {
+	if path == options.StdinPath {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	return strings.Split(string(data), "\n")
+}
+
+// utf16Column converts a 1-based byte column in lines to the UTF-16 code units
+// SARIF counts columns in, or returns it as is without the line
+func utf16Column(lines []string, line, column int) int {
+	if line < 1 || line > len(lines) || column < 1 || column-1 > len(lines[line-1]) {
+		return column
+	}
+
+	units := 1
+	for _, r := range lines[line-1][:column-1] {
+		// runes outside the Basic Multilingual Plane take a surrogate pair
+		if r > 0xFFFF {
+			units += 2
+		} else {
+			units++
+		}
+	}
+
+	return units
+}
+
+// newSARIFResult returns the result for an occurrence, or an error when there
+// is no rule for its class
+func newSARIFResult(o occurrence) (sarifResult, error) {
+	class := o.Class()
+
+	index, ok := sarifRuleIndex["urlstat/"+class]
+	if !ok {
+		return sarifResult{}, fmt.Errorf("no SARIF rule for class '%v' of %v", class, o.URL)
+	}
+
+	level := sarifRules[index].DefaultConfiguration.Level
+	if class == classRedirect && o.PermanentlyRedirected() {
+		level = "warning"
+	}
+
+	return sarifResult{
+		RuleID:    sarifRules[index].ID,
+		RuleIndex: index,
+		Level:     level,
+		Message:   sarifMessage{Text: sarifMessageText(o.result)},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(o.Filepath)},
+			Region:           sarifRegion{StartLine: o.Line, StartColumn: o.Column},
+		}}},
+	}, nil
+}
+
+func sarifMessageText(r result) string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%v: %v error (%v)", r.URL, r.Category, r.Detail)
+	case r.Redirected():
+		return fmt.Sprintf("%v: %v after redirecting to %v", r.URL, r.Status, r.FinalURL)
+	default:
+		return fmt.Sprintf("%v: %v", r.URL, r.Status)
+	}
+}
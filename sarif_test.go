@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintSARIF(t *testing.T) {
+	src := make(chan result, 4)
+	src <- result{link: link{URL: "http://example.com/ok", Locations: []location{{Filepath: "a.md", Line: 1, Column: 1}}}, Status: "200 OK", StatusCode: 200}
+	src <- result{
+		link:       link{URL: "http://example.com/gone", Locations: []location{{Filepath: "docs/b.md", Line: 4, Column: 7}, {Filepath: "a.md", Line: 2, Column: 3}}},
+		Status:     "404 Not Found",
+		StatusCode: 404,
+	}
+	src <- result{
+		link:     link{URL: "https://self-signed.example.com", Locations: []location{{Filepath: "a.md", Line: 5, Column: 1}}},
+		Err:      errors.New("x509: certificate signed by unknown authority"),
+		Category: categoryTLS,
+		Detail:   "certificate signed by unknown authority",
+	}
+	src <- result{
+		link:       link{URL: "http://example.com/moved", Locations: []location{{Filepath: "a.md", Line: 9, Column: 1}}},
+		Status:     "200 OK",
+		StatusCode: 200,
+		Redirects:  []redirect{{StatusCode: 301, Status: "301 Moved Permanently", Location: "https://example.com/moved"}},
+		FinalURL:   "https://example.com/moved",
+	}
+	close(src)
+
+	out := bytes.Buffer{}
+	if err := printSARIF(&out, src, func(result) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	log := sarifLog{}
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("Expected valid JSON, but got %v:\n%v", err, out.String())
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Tool.Driver.Rules) != len(sarifRules) {
+		t.Fatalf("Expected a single SARIF 2.1.0 run with every rule, but got %+v", log)
+	}
+
+	expected := []struct {
+		ruleID string
+		level  string
+		uri    string
+		line   int
+		column int
+	}{
+		{"urlstat/404", "error", "a.md", 2, 3},
+		{"urlstat/tls", "error", "a.md", 5, 1},
+		{"urlstat/redirect", "warning", "a.md", 9, 1},
+		{"urlstat/404", "error", "docs/b.md", 4, 7},
+	}
+
+	results := log.Runs[0].Results
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %v results, but got %+v", len(expected), results)
+	}
+
+	for i, e := range expected {
+		r := results[i]
+		region := r.Locations[0].PhysicalLocation.Region
+
+		if r.RuleID != e.ruleID || r.Level != e.level || log.Runs[0].Tool.Driver.Rules[r.RuleIndex].ID != e.ruleID ||
+			r.Locations[0].PhysicalLocation.ArtifactLocation.URI != e.uri || region.StartLine != e.line || region.StartColumn != e.column {
+			t.Errorf("Expected result %v to be %+v, but got %+v", i, e, r)
+		}
+	}
+}
+
+func TestPrintSARIFUnknownClass(t *testing.T) {
+	src := make(chan result, 1)
+	src <- result{
+		link:     link{URL: "http://example.com", Locations: []location{{Filepath: "a.md", Line: 1, Column: 1}}},
+		Err:      errors.New("unknown"),
+		Category: "unknown",
+	}
+	close(src)
+
+	if err := printSARIF(&bytes.Buffer{}, src, func(result) bool { return true }); err == nil {
+		t.Error("Expected an error for a class without a rule")
+	}
+
+	classes := []string{
+		classNotFound, classClient, classServer, classUnfollowed, classOther, classRedirect,
+		categoryDNS, categoryConnect, categoryTLS, categoryTimeout, categoryProtocol, categoryInvalidURL,
+	}
+
+	for _, class := range classes {
+		if _, ok := sarifRuleIndex["urlstat/"+class]; !ok {
+			t.Errorf("Expected a rule for class '%v'", class)
+		}
+	}
+}
+
+func TestPrintSARIFColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ünicode.md")
+	line := "Ünïcödé 🙂 see http://example.com/gone"
+	writeFile(t, path, "# Title\n"+line+"\n")
+
+	src := make(chan result, 1)
+	src <- result{
+		link:       link{URL: "http://example.com/gone", Locations: []location{{Filepath: path, Line: 2, Column: strings.Index(line, "http") + 1}}},
+		Status:     "404 Not Found",
+		StatusCode: 404,
+	}
+	close(src)
+
+	out := bytes.Buffer{}
+	if err := printSARIF(&out, src, func(result) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	log := sarifLog{}
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	// after 7 letters, a space, a surrogate pair and " see "
+	run := log.Runs[0]
+	if column := run.Results[0].Locations[0].PhysicalLocation.Region.StartColumn; run.ColumnKind != "utf16CodeUnits" || column != 16 {
+		t.Errorf("Expected UTF-16 column 16, but got %v in %v", column, run.ColumnKind)
+	}
+
+	if column := utf16Column(nil, 2, 20); column != 20 {
+		t.Errorf("Expected the byte column without the file, but got %v", column)
+	}
+}
+
+func TestResultClass(t *testing.T) {
+	examples := []struct {
+		r        result
+		expected string
+	}{
+		{result{StatusCode: 200}, ""},
+		{result{StatusCode: 200, Redirects: []redirect{{StatusCode: 302}}}, classRedirect},
+		{result{StatusCode: 404}, classNotFound},
+		{result{StatusCode: 410}, classClient},
+		{result{StatusCode: 503}, classServer},
+		{result{StatusCode: 301}, classUnfollowed},
+		{result{StatusCode: 204}, classOther},
+		{result{Err: errRedirectLoop, Category: categoryProtocol}, categoryProtocol},
+	}
+
+	for _, example := range examples {
+		if actual := example.r.Class(); actual != example.expected {
+			t.Errorf("Expected %+v to be class '%v', but got '%v'", example.r, example.expected, actual)
+		}
+	}
+}
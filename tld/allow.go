@@ -0,0 +1,81 @@
+package tld
+
+import "strings"
+
+// specialUse are the special-use TLDs of RFC 6761 and RFC 6762, which never
+// appear in IANA's list
+var specialUse = List{"local": true, "test": true, "localhost": true, "invalid": true}
+
+var (
+	extraTLDs       = List{}
+	extraHosts      = map[string]bool{}
+	allowSpecialUse = false
+)
+
+// Allow accepts hosts ending in the extra TLDs, and the exact hostnames, as
+// well as hosts ending in a known TLD. It isn't safe to call while hosts are
+// being checked.
+func Allow(tlds, hostnames []string) {
+	extraTLDs = List{}
+	for _, t := range tlds {
+		extraTLDs[normalize(strings.TrimPrefix(t, "*"))] = true
+	}
+
+	extraHosts = map[string]bool{}
+	for _, h := range hostnames {
+		extraHosts[normalize(h)] = true
+	}
+}
+
+// AllowSpecialUse accepts hosts ending in, or named, .local, .test,
+// .localhost and .invalid. It isn't safe to call while hosts are being checked.
+func AllowSpecialUse(allow bool) {
+	allowSpecialUse = allow
+}
+
+// IsKnownHost returns whether hostname, without a port, is an allowed
+// hostname or ends with a known, extra or allowed special-use TLD. A
+// special-use name alone, like "test", isn't a host, so paths like
+// test/fixtures aren't mistaken for URLs. In
+// ModePublicSuffix, rather than a known TLD, it must be under a listed public
+// suffix. Internationalized hostnames are known in either their Unicode or
+// punycode form.
+func IsKnownHost(hostname string) bool {
+	hostname, ok := ToASCII(hostname)
+	if !ok {
+		return false
+	}
+
+	hostname = normalize(hostname)
+	if extraHosts[hostname] {
+		return true
+	}
+
+	dot := strings.LastIndex(hostname, ".")
+	if dot == -1 {
+		return false
+	}
+
+	t := hostname[dot+1:]
+	if extraTLDs[t] || (allowSpecialUse && specialUse[t]) {
+		return true
+	}
+
+	if mode == ModePublicSuffix && suffixes != nil {
+		return suffixes.isRegistrable(hostname)
+	}
+
+	// the built-in list has Unicode TLDs, and IANA's has punycode
+	return known[t] || known[toUnicode(t)]
+}
+
+// normalize lowercases a hostname or TLD, dropping the leading or trailing
+// dots of ".corp" and fully qualified names, and converts it to punycode
+func normalize(name string) string {
+	name = strings.ToLower(strings.Trim(name, "."))
+	if ascii, ok := ToASCII(name); ok {
+		return ascii
+	}
+
+	return name
+}
@@ -0,0 +1,120 @@
+package tld
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// profile maps hostnames like a browser would (UTS #46), while still allowing
+// the underscores found in internal hostnames
+var profile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
+
+// ToASCII returns the punycode form of hostname, like xn--w4r85el8fhu5dnra for
+// 嘉里大酒店, or false when it isn't a valid internationalized hostname
+func ToASCII(hostname string) (string, bool) {
+	ascii, err := profile.ToASCII(strings.Trim(hostname, "."))
+	if err != nil {
+		return "", false
+	}
+
+	return ascii, true
+}
+
+// toUnicode returns the Unicode form of an ASCII label, or the label itself
+func toUnicode(label string) string {
+	if !strings.HasPrefix(label, "xn--") {
+		return label
+	}
+
+	u, err := profile.ToUnicode(label)
+	if err != nil {
+		return label
+	}
+
+	return u
+}
+
+// scriptSets are the combinations of scripts that are commonly written
+// together, from the highly restrictive level of UTS #39
+var scriptSets = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// MixedScripts returns the scripts of the first label of hostname that mixes
+// scripts not commonly written together, like Cyrillic and Latin in
+// "pаypal" with a Cyrillic "а", or nil when no label does
+func MixedScripts(hostname string) []string {
+	ascii, ok := ToASCII(hostname)
+	if !ok {
+		return nil
+	}
+
+	for _, label := range strings.Split(ascii, ".") {
+		scripts := scriptsOf(toUnicode(label))
+		if len(scripts) > 1 && !isCommonScriptSet(scripts) {
+			return scripts
+		}
+	}
+
+	return nil
+}
+
+// scriptsOf returns the sorted scripts of the letters in s, ignoring digits,
+// punctuation and marks shared between scripts
+func scriptsOf(s string) []string {
+	found := map[string]bool{}
+
+	for _, r := range s {
+		if r < unicode.MaxASCII {
+			if unicode.IsLetter(r) {
+				found["Latin"] = true
+			}
+			continue
+		}
+
+		for name, table := range unicode.Scripts {
+			if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+				found[name] = true
+				break
+			}
+		}
+	}
+
+	scripts := make([]string, 0, len(found))
+	for name := range found {
+		scripts = append(scripts, name)
+	}
+	sort.Strings(scripts)
+
+	return scripts
+}
+
+func isCommonScriptSet(scripts []string) bool {
+	for _, set := range scriptSets {
+		within := true
+		for _, script := range scripts {
+			within = within && contains(set, script)
+		}
+
+		if within {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
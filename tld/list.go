@@ -0,0 +1,190 @@
+package tld
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultURL is where IANA publishes the list of TLDs
+const DefaultURL = "https://data.iana.org/TLD/tlds-alpha-by-domain.txt"
+
+// cacheFile is the name of the cached list within the user cache directory
+const cacheFile = "tlds-alpha-by-domain.txt"
+
+// List is a set of lowercase TLDs
+type List map[string]bool
+
+// known are the TLDs HasKnownTLD accepts
+var known = List(tlds)
+
+// Use replaces the TLDs HasKnownTLD accepts with list, or the compiled-in
+// list when list is nil. It isn't safe to call while hosts are being checked.
+func Use(list List) {
+	if list == nil {
+		list = tlds
+	}
+
+	known = list
+}
+
+// Parse reads a list in the format of IANA's tlds-alpha-by-domain.txt: a TLD
+// per line, with comments starting with '#'
+func Parse(r io.Reader) (List, error) {
+	list := List{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !isLabel(line) {
+			return nil, fmt.Errorf("line %v: invalid TLD '%v'", n, line)
+		}
+
+		list[strings.ToLower(line)] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, errors.New("no TLDs found")
+	}
+
+	return list, nil
+}
+
+// ReadFile parses the list in the file at path
+func ReadFile(path string) (List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return list, nil
+}
+
+// CachePath returns where the list of TLDs is cached in the user cache directory
+func CachePath() (string, error) {
+	return cachePath(cacheFile)
+}
+
+// PublicSuffixCachePath returns where the Public Suffix List is cached in the
+// user cache directory
+func PublicSuffixCachePath() (string, error) {
+	return cachePath(publicSuffixCacheFile)
+}
+
+func cachePath(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "urlstat", name), nil
+}
+
+// Update downloads the list of TLDs from url and, if it parses, replaces the
+// file at path with it, returning the number of TLDs
+func Update(client *http.Client, url, path string) (int, error) {
+	return download(client, url, path, func(r io.Reader) (int, error) {
+		list, err := Parse(r)
+		return len(list), err
+	})
+}
+
+// UpdatePublicSuffixList downloads the Public Suffix List from url and, if it
+// parses, replaces the file at path with it, returning the number of rules
+func UpdatePublicSuffixList(client *http.Client, url, path string) (int, error) {
+	return download(client, url, path, func(r io.Reader) (int, error) {
+		list, err := ParsePublicSuffixList(r)
+		if err != nil {
+			return 0, err
+		}
+
+		return list.Len(), nil
+	})
+}
+
+// download replaces the file at path with the response from url, if parse
+// accepts it, returning the count from parse
+func download(client *http.Client, url, path string, parse func(io.Reader) (int, error)) (int, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%v responded %v", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := parse(bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%v: %v", url, err)
+	}
+
+	if err := writeFile(path, body); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// writeFile replaces the file at path with data, creating its directory, so
+// that readers never see a partially written file
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// isLabel returns whether s is made of letters, digits and hyphens, which
+// covers punycode TLDs like XN--VERMGENSBERATER-CTB
+func isLabel(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+
+	return len(s) > 0
+}
@@ -0,0 +1,193 @@
+package tld
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultPublicSuffixURL is where the Public Suffix List is published
+const DefaultPublicSuffixURL = "https://publicsuffix.org/list/public_suffix_list.dat"
+
+// publicSuffixCacheFile is the name of the cached Public Suffix List within
+// the user cache directory
+const publicSuffixCacheFile = "public_suffix_list.dat"
+
+// Modes of checking whether a host is known
+const (
+	ModeIANA         = "iana"         // the host ends in a TLD
+	ModePublicSuffix = "publicsuffix" // the host is under a public suffix
+)
+
+var (
+	mode     = ModeIANA
+	suffixes *SuffixList
+)
+
+// SuffixList is a parsed Public Suffix List
+type SuffixList struct {
+	rules      map[string]bool // rule to whether it's in the ICANN section
+	wildcards  map[string]bool // *.rule, keyed by rule
+	exceptions map[string]bool // !rule, keyed by rule
+}
+
+// Suffix is the public suffix of a host
+type Suffix struct {
+	Name   string // e.g. co.uk
+	ICANN  bool   // the rule is in the ICANN section, not the private section
+	Listed bool   // a rule matched, rather than the implicit * rule
+}
+
+// SetMode sets how IsKnownHost checks hosts, ModeIANA or ModePublicSuffix.
+// Without a list given to UsePublicSuffixList, ModePublicSuffix acts like
+// ModeIANA. It isn't safe to call while hosts are being checked.
+func SetMode(m string) {
+	mode = m
+}
+
+// UsePublicSuffixList sets the list of public suffixes used by
+// RegistrableDomain and ModePublicSuffix. It isn't safe to call while hosts
+// are being checked.
+func UsePublicSuffixList(list *SuffixList) {
+	suffixes = list
+}
+
+// RegistrableDomain returns the domain of host registered under a public
+// suffix, like example.co.uk for www.example.co.uk, or host itself without a
+// list of public suffixes or a registrable domain
+func RegistrableDomain(host string) string {
+	host = normalize(host)
+	if suffixes == nil {
+		return host
+	}
+
+	if domain, ok := suffixes.RegistrableDomain(host); ok {
+		return domain
+	}
+
+	return host
+}
+
+// ParsePublicSuffixList reads a list in the format of the Public Suffix List:
+// a rule per line, which may be a wildcard (*.ck) or exception (!www.ck), and
+// comments starting with "//" that mark the ICANN and private sections
+func ParsePublicSuffixList(r io.Reader) (*SuffixList, error) {
+	list := &SuffixList{rules: map[string]bool{}, wildcards: map[string]bool{}, exceptions: map[string]bool{}}
+	icann := false
+	count := 0
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "// ===BEGIN ICANN DOMAINS==="):
+			icann = true
+			continue
+		case strings.HasPrefix(line, "// ===END ICANN DOMAINS==="), strings.HasPrefix(line, "// ===BEGIN PRIVATE DOMAINS==="):
+			icann = false
+			continue
+		case len(line) == 0 || strings.HasPrefix(line, "//"):
+			continue
+		}
+
+		rule := normalize(strings.Fields(line)[0])
+		rules := list.rules
+
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			rule, rules = strings.TrimPrefix(rule, "!"), list.exceptions
+		case strings.HasPrefix(rule, "*."):
+			rule, rules = strings.TrimPrefix(rule, "*."), list.wildcards
+		}
+
+		if len(rule) == 0 || strings.ContainsAny(rule, "*!/") {
+			return nil, fmt.Errorf("line %v: invalid rule '%v'", n, line)
+		}
+
+		rules[rule] = icann
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, errors.New("no rules found")
+	}
+
+	return list, nil
+}
+
+// ReadPublicSuffixFile parses the Public Suffix List in the file at path
+func ReadPublicSuffixFile(path string) (*SuffixList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list, err := ParsePublicSuffixList(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return list, nil
+}
+
+// Len returns the number of rules in the list
+func (l *SuffixList) Len() int {
+	return len(l.rules) + len(l.wildcards) + len(l.exceptions)
+}
+
+// PublicSuffix returns the public suffix of host, using the longest matching
+// rule, an exception to a wildcard, or the last label when no rule matches
+func (l *SuffixList) PublicSuffix(host string) Suffix {
+	labels := strings.Split(normalize(host), ".")
+
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+
+		if icann, ok := l.exceptions[name]; ok {
+			return Suffix{Name: strings.Join(labels[i+1:], "."), ICANN: icann, Listed: true}
+		}
+
+		if icann, ok := l.rules[name]; ok {
+			return Suffix{Name: name, ICANN: icann, Listed: true}
+		}
+
+		if i+1 < len(labels) {
+			parent := strings.Join(labels[i+1:], ".")
+			if icann, ok := l.wildcards[parent]; ok {
+				return Suffix{Name: name, ICANN: icann, Listed: true}
+			}
+		}
+	}
+
+	return Suffix{Name: labels[len(labels)-1]}
+}
+
+// RegistrableDomain returns the public suffix of host and the label before it,
+// or false when host is itself a public suffix
+func (l *SuffixList) RegistrableDomain(host string) (string, bool) {
+	host = normalize(host)
+	suffix := l.PublicSuffix(host)
+
+	if host == suffix.Name || !strings.HasSuffix(host, "."+suffix.Name) {
+		return "", false
+	}
+
+	rest := strings.TrimSuffix(host, "."+suffix.Name)
+	return rest[strings.LastIndex(rest, ".")+1:] + "." + suffix.Name, true
+}
+
+// isRegistrable returns whether host is, or is under, a registrable domain of
+// a listed public suffix
+func (l *SuffixList) isRegistrable(host string) bool {
+	_, ok := l.RegistrableDomain(host)
+	return ok && l.PublicSuffix(host).Listed
+}
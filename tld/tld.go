@@ -16,7 +16,7 @@ func HasKnownTLD(host string) bool {
 	tldStr = stripAfter(tldStr, "#")
 	tldStr = stripAfter(tldStr, "?")
 
-	return tlds[tldStr]
+	return known[tldStr]
 }
 
 func stripAfter(s string, sep string) string {
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jmks/urlstat/tld"
@@ -25,3 +31,358 @@ func TestHasKnownTLD(t *testing.T) {
 		}
 	}
 }
+
+const ianaList = `# Version 2024101800, Last Updated Fri Oct 18 07:07:01 2024 UTC
+COM
+DEV
+XN--VERMGENSBERATER-CTB
+`
+
+func TestParseTLDs(t *testing.T) {
+	list, err := tld.Parse(strings.NewReader(ianaList))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 3 || !list["com"] || !list["dev"] || !list["xn--vermgensberater-ctb"] {
+		t.Errorf("Expected the three lowercase TLDs, but got %v", list)
+	}
+
+	for _, invalid := range []string{"", "# only a comment\n", "COM\n<html>\n"} {
+		if _, err := tld.Parse(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected %q to be an invalid list", invalid)
+		}
+	}
+}
+
+func TestUseTLDs(t *testing.T) {
+	defer tld.Use(nil)
+
+	tld.Use(tld.List{"dev": true})
+	if !tld.HasKnownTLD("example.dev") || tld.HasKnownTLD("example.com") {
+		t.Error("Expected only the TLDs in use to be known")
+	}
+
+	tld.Use(nil)
+	if !tld.HasKnownTLD("example.com") {
+		t.Error("Expected the built-in TLDs to be known again")
+	}
+}
+
+func TestLoadTLDs(t *testing.T) {
+	defer tld.Use(nil)
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	var warnings bytes.Buffer
+
+	if err := loadTLDs("", &warnings); err != nil || warnings.Len() > 0 || !tld.HasKnownTLD("example.com") {
+		t.Errorf("Expected the built-in TLDs without a cached list, but got %v %v", err, warnings.String())
+	}
+
+	if err := loadPublicSuffixList(""); err == nil {
+		t.Error("Expected an error without a cached public suffix list")
+	}
+
+	cached, err := tld.CachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, cached, "<html>not a list</html>")
+
+	if err := loadTLDs("", &warnings); err != nil || !strings.Contains(warnings.String(), "urlstat tld update") || !tld.HasKnownTLD("example.com") {
+		t.Errorf("Expected a warning and the built-in TLDs for an invalid cached list, but got %v %v", err, warnings.String())
+	}
+
+	path := filepath.Join(dir, "tlds.txt")
+	writeFile(t, path, "ZZZ\n")
+
+	if err := loadTLDs(path, io.Discard); err != nil || !tld.HasKnownTLD("example.zzz") || tld.HasKnownTLD("example.com") {
+		t.Errorf("Expected the TLDs from -tld-file, but got %v", err)
+	}
+
+	for _, invalid := range []string{filepath.Join(dir, "missing.txt"), cached} {
+		if err := loadTLDs(invalid, io.Discard); err == nil {
+			t.Errorf("Expected an error for the -tld-file %v", invalid)
+		}
+	}
+}
+
+func TestIsTLDCommand(t *testing.T) {
+	examples := map[string]bool{
+		"tld update":         true,
+		"tld update -list x": true,
+		"tld":                false,
+		"tld docs":           false,
+		"./tld update":       false,
+		"docs tld update":    false,
+	}
+
+	for args, expected := range examples {
+		if actual := isTLDCommand(strings.Fields(args)); actual != expected {
+			t.Errorf("Expected '%v' to be %v, but got %v", args, expected, actual)
+		}
+	}
+}
+
+func TestTLDUpdate(t *testing.T) {
+	defer tld.Use(nil)
+
+	files := http.NewServeMux()
+	files.HandleFunc("/tlds-alpha-by-domain.txt", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, ianaList)
+	})
+	files.HandleFunc("/garbage.txt", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html>not a list</html>")
+	})
+	server := httptest.NewServer(files)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cache", "tlds-alpha-by-domain.txt")
+	var stdout, stderr bytes.Buffer
+
+	code := runTLD([]string{"update", "-url", server.URL + "/tlds-alpha-by-domain.txt", "-file", path}, &stdout, &stderr)
+	if code != exitOK || !strings.HasPrefix(stdout.String(), "Saved 3 TLDs") {
+		t.Fatalf("Expected the list to be saved, but got %v: %v%v", code, stdout.String(), stderr.String())
+	}
+
+	if err := loadTLDs(path, io.Discard); err != nil || !tld.HasKnownTLD("example.dev") || tld.HasKnownTLD("example.org") {
+		t.Errorf("Expected the updated list to be used, but got %v", err)
+	}
+
+	for _, url := range []string{server.URL + "/garbage.txt", server.URL + "/missing.txt"} {
+		stderr.Reset()
+
+		if code := runTLD([]string{"update", "-url", url, "-file", path}, io.Discard, &stderr); code != exitInternal {
+			t.Errorf("Expected %v to fail, but got %v", url, code)
+		}
+	}
+
+	if list, err := tld.ReadFile(path); err != nil || len(list) != 3 {
+		t.Errorf("Expected a failed update to keep the cached list, but got %v (%v)", list, err)
+	}
+
+	files.HandleFunc("/public_suffix_list.dat", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, publicSuffixList)
+	})
+
+	pslPath := filepath.Join(filepath.Dir(path), "public_suffix_list.dat")
+	stdout.Reset()
+
+	code = runTLD([]string{"update", "-list", "publicsuffix", "-url", server.URL + "/public_suffix_list.dat", "-file", pslPath}, &stdout, io.Discard)
+	if code != exitOK || !strings.HasPrefix(stdout.String(), "Saved 10 public suffix rules") {
+		t.Errorf("Expected the public suffix list to be saved, but got %v: %v", code, stdout.String())
+	}
+
+	if err := loadPublicSuffixList(pslPath); err != nil || tld.RegistrableDomain("www.example.co.uk") != "example.co.uk" {
+		t.Errorf("Expected the updated public suffix list to be used, but got %v", err)
+	}
+	tld.UsePublicSuffixList(nil)
+
+	for _, args := range [][]string{{"download"}, {"update", "-list", "other"}} {
+		if code := runTLD(args, io.Discard, io.Discard); code != exitUsage {
+			t.Errorf("Expected %v to be a usage error, but got %v", args, code)
+		}
+	}
+}
+
+func TestIsKnownHost(t *testing.T) {
+	defer tld.Allow(nil, nil)
+	defer tld.AllowSpecialUse(false)
+
+	tld.Allow([]string{"corp", ".internal", "*.lan"}, []string{"jenkins", "Wiki.example"})
+
+	examples := map[string]bool{
+		"example.com":       true,
+		"EXAMPLE.COM.":      true,
+		"example.badtld":    false,
+		"build.corp":        true,
+		"api.internal":      true,
+		"printer.lan":       true,
+		"jenkins":           true,
+		"JENKINS":           true,
+		"jenkins.example":   false,
+		"wiki.example":      true,
+		"other":             false,
+		"printer.local":     false,
+		"app.test":          false,
+		"localhost":         false,
+		"corp":              false,
+		"not-jenkins.other": false,
+	}
+
+	for host, expected := range examples {
+		if actual := tld.IsKnownHost(host); actual != expected {
+			t.Errorf("Expected %v to be %v, but got %v", host, expected, actual)
+		}
+	}
+
+	tld.AllowSpecialUse(true)
+	for _, host := range []string{"printer.local", "app.test", "web.localhost", "nothing.invalid"} {
+		if !tld.IsKnownHost(host) {
+			t.Errorf("Expected special-use %v to be known", host)
+		}
+	}
+
+	for _, name := range []string{"test", "local", "invalid", "localhost"} {
+		if tld.IsKnownHost(name) {
+			t.Errorf("Expected the bare special-use name %v not to be a host", name)
+		}
+	}
+}
+
+const publicSuffixList = `// This Source Code Form is subject to the terms of the Mozilla Public License
+
+// ===BEGIN ICANN DOMAINS===
+
+com
+uk
+co.uk
+// ck : https://en.wikipedia.org/wiki/.ck
+*.ck
+!www.ck
+jp
+*.kobe.jp
+!city.kobe.jp
+
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+
+blogspot.com
+*.compute.amazonaws.com
+
+// ===END PRIVATE DOMAINS===
+`
+
+func TestPublicSuffix(t *testing.T) {
+	list, err := tld.ParsePublicSuffixList(strings.NewReader(publicSuffixList))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	examples := []struct {
+		host, suffix, domain string
+		icann, listed        bool
+	}{
+		{"example.com", "com", "example.com", true, true},
+		{"www.example.com", "com", "example.com", true, true},
+		{"WWW.Example.COM.", "com", "example.com", true, true},
+		{"com", "com", "", true, true},
+		{"example.co.uk", "co.uk", "example.co.uk", true, true},
+		{"www.evil.co.uk", "co.uk", "evil.co.uk", true, true},
+		{"co.uk", "co.uk", "", true, true},
+		{"foo.blogspot.com", "blogspot.com", "foo.blogspot.com", false, true},
+		{"a.foo.blogspot.com", "blogspot.com", "foo.blogspot.com", false, true},
+		{"www.ck", "ck", "www.ck", true, true},
+		{"test.ck", "test.ck", "", true, true},
+		{"a.test.ck", "test.ck", "a.test.ck", true, true},
+		{"city.kobe.jp", "kobe.jp", "city.kobe.jp", true, true},
+		{"a.b.kobe.jp", "b.kobe.jp", "a.b.kobe.jp", true, true},
+		{"ec2-1.eu-west-1.compute.amazonaws.com", "eu-west-1.compute.amazonaws.com", "ec2-1.eu-west-1.compute.amazonaws.com", false, true},
+		{"example.junk", "junk", "example.junk", false, false},
+	}
+
+	for _, example := range examples {
+		suffix := list.PublicSuffix(example.host)
+		if suffix.Name != example.suffix || suffix.ICANN != example.icann || suffix.Listed != example.listed {
+			t.Errorf("Expected the public suffix of %v to be %v (icann %v, listed %v), but got %+v", example.host, example.suffix, example.icann, example.listed, suffix)
+		}
+
+		domain, ok := list.RegistrableDomain(example.host)
+		if domain != example.domain || ok != (len(example.domain) > 0) {
+			t.Errorf("Expected the registrable domain of %v to be '%v', but got '%v'", example.host, example.domain, domain)
+		}
+	}
+
+	for _, invalid := range []string{"", "// only a comment\n", "com\n*.*.uk\n"} {
+		if _, err := tld.ParsePublicSuffixList(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected %q to be an invalid list", invalid)
+		}
+	}
+}
+
+func TestPublicSuffixMode(t *testing.T) {
+	defer tld.SetMode(tld.ModeIANA)
+	defer tld.UsePublicSuffixList(nil)
+
+	list, err := tld.ParsePublicSuffixList(strings.NewReader(publicSuffixList))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tld.RegistrableDomain("www.example.co.uk") != "www.example.co.uk" {
+		t.Error("Expected hosts to be their own domain without a public suffix list")
+	}
+
+	tld.UsePublicSuffixList(list)
+
+	if !tld.IsKnownHost("example.org") || tld.RegistrableDomain("www.example.co.uk") != "example.co.uk" {
+		t.Error("Expected IANA TLDs to be known, and registrable domains to use the public suffix list")
+	}
+
+	tld.SetMode(tld.ModePublicSuffix)
+
+	examples := map[string]bool{
+		"example.com":      true,
+		"www.evil.co.uk":   true,
+		"co.uk":            false,
+		"foo.blogspot.com": true,
+		"blogspot.com":     false,
+		"test.ck":          false,
+		"example.org":      false,
+		"example.junk":     false,
+	}
+
+	for host, expected := range examples {
+		if actual := tld.IsKnownHost(host); actual != expected {
+			t.Errorf("Expected %v to be %v with a public suffix list, but got %v", host, expected, actual)
+		}
+	}
+}
+
+func TestInternationalizedHosts(t *testing.T) {
+	defer tld.Use(nil)
+
+	ascii, ok := tld.ToASCII("Bücher.example")
+	if !ok || ascii != "xn--bcher-kva.example" {
+		t.Errorf("Expected the punycode form of Bücher.example, but got %v", ascii)
+	}
+
+	if _, ok := tld.ToASCII("xn--a.com"); ok {
+		t.Error("Expected invalid punycode to be rejected")
+	}
+
+	for _, list := range []tld.List{nil, {"xn--w4r85el8fhu5dnra": true, "com": true}} {
+		tld.Use(list)
+
+		for _, host := range []string{"hotel.嘉里大酒店", "hotel.xn--w4r85el8fhu5dnra", "HOTEL.XN--W4R85EL8FHU5DNRA", "bücher.com", "xn--bcher-kva.com"} {
+			if !tld.IsKnownHost(host) {
+				t.Errorf("Expected %v to be known with the list %v", host, list)
+			}
+		}
+	}
+}
+
+func TestMixedScripts(t *testing.T) {
+	examples := map[string]string{
+		"example.com":           "",
+		"bücher.de":             "",
+		"пример.рф":             "",
+		"xn--e1afmkfd.xn--p1ai": "",
+		"аpple.com":             "Cyrillic, Latin",
+		"xn--pple-43d.com":      "Cyrillic, Latin",
+		"google.cοm":            "Greek, Latin",
+		"日本語テキスト.jp":            "",
+		"ソニーsony.jp":            "",
+		"한국abc.kr":              "",
+		"ไทยabc.com":            "Latin, Thai",
+	}
+
+	for host, expected := range examples {
+		if actual := strings.Join(tld.MixedScripts(host), ", "); actual != expected {
+			t.Errorf("Expected %v to mix '%v', but got '%v'", host, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/jmks/urlstat/tld"
+)
+
+// loadTLDs makes the list in path the known TLDs, or the list cached by
+// 'urlstat tld update' when path is empty. Without either, the built-in list
+// is kept. A cached list that can't be read keeps the built-in list too, with
+// a warning written to warnings.
+func loadTLDs(path string, warnings io.Writer) error {
+	_, err := loadList(path, tld.CachePath, "urlstat tld update", func(path string) error {
+		list, err := tld.ReadFile(path)
+		if err == nil {
+			tld.Use(list)
+		}
+
+		return err
+	})
+
+	if err != nil && len(path) == 0 {
+		fmt.Fprintf(warnings, "Warning '%v', using the built-in list\n", err)
+		return nil
+	}
+
+	return err
+}
+
+// loadPublicSuffixList makes the list in path, or the list cached by
+// 'urlstat tld update -list publicsuffix' when path is empty, the list of
+// public suffixes. There is no built-in list.
+func loadPublicSuffixList(path string) error {
+	found, err := loadList(path, tld.PublicSuffixCachePath, "urlstat tld update -list publicsuffix", func(path string) error {
+		list, err := tld.ReadPublicSuffixFile(path)
+		if err == nil {
+			tld.UsePublicSuffixList(list)
+		}
+
+		return err
+	})
+
+	if !found && err == nil {
+		return errors.New("no public suffix list, run 'urlstat tld update -list publicsuffix' or give -psl-file")
+	}
+
+	return err
+}
+
+// loadList reads the file at path, or the cached file when path is empty,
+// returning whether there was a file to read. An invalid cached file is
+// reported with the command that replaces it.
+func loadList(path string, cache func() (string, error), update string, read func(string) error) (bool, error) {
+	if len(path) > 0 {
+		return true, read(path)
+	}
+
+	cached, err := cache()
+	if err != nil {
+		return false, nil
+	}
+
+	err = read(cached)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("cached list is invalid, run '%v': %v", update, err)
+	}
+
+	return true, nil
+}
+
+// tldLists are the lists 'urlstat tld update' can download: where from, where
+// to, and how to parse them
+var tldLists = map[string]struct {
+	url    string
+	cache  func() (string, error)
+	update func(*http.Client, string, string) (int, error)
+	noun   string
+}{
+	tld.ModeIANA:         {tld.DefaultURL, tld.CachePath, tld.Update, "TLDs"},
+	tld.ModePublicSuffix: {tld.DefaultPublicSuffixURL, tld.PublicSuffixCachePath, tld.UpdatePublicSuffixList, "public suffix rules"},
+}
+
+// runTLD runs the 'urlstat tld' commands, writing progress to stdout and
+// errors to stderr
+func runTLD(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("urlstat tld update", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	listName := flags.String("list", tld.ModeIANA, "list to download: iana (TLDs, in the format of tlds-alpha-by-domain.txt) or publicsuffix (the Public Suffix List)")
+	url := flags.String("url", "", "URL of the list (defaults to "+tld.DefaultURL+" or "+tld.DefaultPublicSuffixURL+")")
+	path := flags.String("file", "", "where to save the list, which urlstat uses when -tld-file or -psl-file aren't given (defaults to the user cache directory)")
+	timeout := flags.Duration("timeout", 30*time.Second, "maximum time to download the list")
+
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage of URLstat: urlstat tld update [options]")
+		fmt.Fprintln(stderr, "Downloads the list of known TLDs or public suffixes")
+		fmt.Fprintln(stderr, "Options:")
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != "update" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+
+	list, ok := tldLists[*listName]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown -list '%v'\n", *listName)
+	}
+
+	if len(*url) == 0 {
+		*url = list.url
+	}
+
+	if len(*path) == 0 && ok {
+		*path, _ = list.cache()
+		if len(*path) == 0 {
+			fmt.Fprintln(stderr, "No user cache directory, -file is required")
+		}
+	}
+
+	if !ok || flags.NArg() > 0 || len(*path) == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	count, err := list.update(&http.Client{Timeout: *timeout}, *url, *path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error '%v'\n", err)
+		return exitInternal
+	}
+
+	fmt.Fprintf(stdout, "Saved %v %v from %v to %v\n", count, list.noun, *url, *path)
+	return exitOK
+}
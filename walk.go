@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmks/urlstat/glob"
+	"github.com/jmks/urlstat/ignore"
+)
+
+// walkConfig holds the options that change which files are found in directories
+type walkConfig struct {
+	include []string
+	exclude []string
+}
+
+// skippedDirs are version control directories, which are never walked
+var skippedDirs = map[string]bool{".git": true, ".hg": true, ".svn": true}
+
+// walkDir calls visit for every included file under root, recursively and
+// following symlinks. Files and directories matched by ignore files, including
+// those in the parent directories of a repository root is within, are
+// skipped, as are directories already visited through another path, such as
+// by a symlink loop.
+func walkDir(root string, cfg walkConfig, visit func(string), errLog *errorLog) {
+	w := walker{root: root, cfg: cfg, visit: visit, errLog: errLog, visited: make(map[string]bool)}
+
+	var rules ignore.Rules
+	if repo, base, ok := repositoryRoot(root); ok {
+		w.base = base
+		rules = w.readParentIgnoreFiles(repo)
+	}
+
+	w.walk(root, rules)
+}
+
+type walker struct {
+	root    string
+	base    string // root's slash separated path within its repository, which ignore rules are relative to
+	cfg     walkConfig
+	visit   func(string)
+	errLog  *errorLog
+	visited map[string]bool
+}
+
+// repositoryRoot returns the closest directory at or above dir with a .git,
+// and the slash separated path of dir within it ("" for the root itself), or
+// false when dir isn't in a repository
+func repositoryRoot(dir string) (string, string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+
+	for repo := abs; ; {
+		if _, err := os.Stat(filepath.Join(repo, ".git")); err == nil {
+			rel, err := filepath.Rel(repo, abs)
+			if err != nil {
+				return "", "", false
+			}
+
+			if rel == "." {
+				rel = ""
+			}
+
+			return repo, filepath.ToSlash(rel), true
+		}
+
+		parent := filepath.Dir(repo)
+		if parent == repo {
+			return "", "", false
+		}
+		repo = parent
+	}
+}
+
+// readParentIgnoreFiles returns the rules of the ignore files from the
+// repository root repo down to, but not including, the root of the walk
+func (w *walker) readParentIgnoreFiles(repo string) ignore.Rules {
+	var rules ignore.Rules
+	if len(w.base) == 0 {
+		return rules
+	}
+
+	dir, base := repo, ""
+	for _, name := range strings.Split(w.base, "/") {
+		rules = w.readIgnoreFiles(dir, base, rules)
+		dir, base = filepath.Join(dir, name), path.Join(base, name)
+	}
+
+	return rules
+}
+
+func (w *walker) walk(dir string, rules ignore.Rules) {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		w.errLog.add(err)
+		return
+	}
+
+	if w.visited[real] {
+		return
+	}
+	w.visited[real] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.errLog.add(err)
+		return
+	}
+
+	rules = w.readIgnoreFiles(dir, w.ignorePath(dir), rules)
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// a broken symlink isn't worth failing over
+			if entry.Type()&os.ModeSymlink == 0 {
+				w.errLog.add(err)
+			}
+			continue
+		}
+
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			w.errLog.add(err)
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rules.Ignored(w.ignorePath(path), info.IsDir()) {
+			continue
+		}
+
+		if info.IsDir() {
+			if !skippedDirs[entry.Name()] && !matchesAny(w.cfg.exclude, rel) {
+				w.walk(path, rules)
+			}
+		} else if info.Mode().IsRegular() && w.isIncluded(rel) {
+			w.visit(path)
+		}
+	}
+}
+
+// ignorePath returns the slash separated path of p, under the root of the
+// walk, that ignore rules match against
+func (w *walker) ignorePath(p string) string {
+	rel, err := filepath.Rel(w.root, p)
+	if err != nil || rel == "." {
+		rel = ""
+	}
+
+	return path.Join(w.base, filepath.ToSlash(rel))
+}
+
+// readIgnoreFiles returns rules extended with the rules of the ignore files in
+// dir, whose path ignore rules match against is base
+func (w *walker) readIgnoreFiles(dir, base string, rules ignore.Rules) ignore.Rules {
+	for _, name := range ignore.Filenames {
+		file, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			w.errLog.add(err)
+			continue
+		}
+
+		parsed, err := ignore.Parse(file, base)
+		file.Close()
+		if err != nil {
+			w.errLog.add(err)
+		}
+
+		// copied so sibling directories don't share each other's rules
+		rules = append(rules[:len(rules):len(rules)], parsed...)
+	}
+
+	return rules
+}
+
+func (w *walker) isIncluded(rel string) bool {
+	if matchesAny(w.cfg.exclude, rel) {
+		return false
+	}
+
+	return len(w.cfg.include) == 0 || matchesAny(w.cfg.include, rel)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if glob.Match(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestWalkDir(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"README.md", "docs/index.md", "docs/guide/install.md", "docs/site.html", "vendor/lib/README.md", ".git/HEAD"} {
+		writeFile(t, filepath.Join(root, path), "http://example.com")
+	}
+
+	// a symlink back up the tree would loop forever if followed blindly
+	if err := os.Symlink(filepath.Join(root, "docs"), filepath.Join(root, "docs", "guide", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	examples := []struct {
+		cfg      walkConfig
+		expected []string
+	}{
+		{walkConfig{}, []string{"README.md", "docs/guide/install.md", "docs/index.md", "docs/site.html", "vendor/lib/README.md"}},
+		{walkConfig{include: []string{"*.md"}, exclude: []string{"vendor/**"}}, []string{"README.md", "docs/guide/install.md", "docs/index.md"}},
+		{walkConfig{include: []string{"docs/*"}}, []string{"docs/index.md", "docs/site.html"}},
+		{walkConfig{exclude: []string{"docs"}}, []string{"README.md", "vendor/lib/README.md"}},
+	}
+
+	for _, example := range examples {
+		var actual []string
+		errs := bytes.Buffer{}
+		walkDir(root, example.cfg, func(path string) {
+			rel, _ := filepath.Rel(root, path)
+			actual = append(actual, filepath.ToSlash(rel))
+		}, &errorLog{w: &errs})
+		sort.Strings(actual)
+
+		if !stringSlicesEqual(actual, example.expected) {
+			t.Errorf("Expected %+v to walk %v, but got %v", example.cfg, example.expected, actual)
+		}
+
+		if errs.Len() > 0 {
+			t.Errorf("Expected no errors, but got %v", errs.String())
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalkDirIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"README.md", "notes.txt", "build/out.md", "site/index.html", "site/about.html", "site/draft.md"} {
+		writeFile(t, filepath.Join(root, path), "http://example.com")
+	}
+
+	writeFile(t, filepath.Join(root, ".gitignore"), "build/\n*.txt\n")
+	writeFile(t, filepath.Join(root, ".urlstatignore"), "!notes.txt\n")
+	writeFile(t, filepath.Join(root, "site", ".ignore"), "*.html\n!index.html\ndraft.md\n")
+
+	var actual []string
+	walkDir(root, walkConfig{}, func(path string) {
+		rel, _ := filepath.Rel(root, path)
+		actual = append(actual, filepath.ToSlash(rel))
+	}, &errorLog{w: &bytes.Buffer{}})
+	sort.Strings(actual)
+
+	expected := []string{".gitignore", ".urlstatignore", "README.md", "notes.txt", "site/.ignore", "site/index.html"}
+	if !stringSlicesEqual(actual, expected) {
+		t.Errorf("Expected ignore files to leave %v, but got %v", expected, actual)
+	}
+}
+
+func TestWalkDirParentIgnoreFiles(t *testing.T) {
+	repo := t.TempDir()
+	for _, path := range []string{"docs/a.md", "docs/_build/a.md", "docs/guide/b.md", "docs/guide/draft.md", "docs/notes.txt"} {
+		writeFile(t, filepath.Join(repo, path), "http://example.com")
+	}
+
+	writeFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(repo, ".gitignore"), "docs/_build/\n/docs/notes.txt\n/a.md\n")
+	writeFile(t, filepath.Join(repo, "docs", ".gitignore"), "guide/draft.md\n")
+
+	// scanned from within the repository's docs directory
+	root := filepath.Join(repo, "docs")
+
+	var actual []string
+	walkDir(root, walkConfig{}, func(path string) {
+		rel, _ := filepath.Rel(root, path)
+		actual = append(actual, filepath.ToSlash(rel))
+	}, &errorLog{w: &bytes.Buffer{}})
+	sort.Strings(actual)
+
+	expected := []string{".gitignore", "a.md", "guide/b.md"}
+	if !stringSlicesEqual(actual, expected) {
+		t.Errorf("Expected the repository's ignore files to leave %v, but got %v", expected, actual)
+	}
+}